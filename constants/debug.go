@@ -0,0 +1,5 @@
+package constants
+
+// Search replay: steps advanced per frame while playing and while a scrub key is held
+const DebugPlaySpeed int = 4
+const DebugScrubSpeed int = 10
@@ -1,3 +1,42 @@
 package constants
 
+import "time"
+
 const GridSize float32 = 30
+
+// Navigation grid resolution and the height it covers above the terrain
+const CellSize float32 = 0.5
+const NavHeight float32 = 4
+
+// Ground movement limits: highest step between neighbouring cells, steepest
+// slope in degrees and the extra cost per unit climbed
+const MaxStepHeight float32 = 0.4
+const MaxSlope float32 = 40
+const ClimbCost float64 = 1
+
+// Upper bound on the nodes a single path query may expand
+const MaxExpandedNodes int = 20000
+
+// Asynchronous path queries: worker goroutines, pending request capacity and
+// how long a single query may run
+const PathWorkers int = 2
+const PathQueueSize int = 64
+const PathTimeout = 250 * time.Millisecond
+
+// Number of paths kept by the path cache
+const PathCacheSize int = 128
+
+// Agent size the navigation mesh is built for
+const NavAgentRadius float32 = 0.4
+const NavAgentHeight float32 = 2
+
+// Route the player over the navigation mesh instead of the grid. The mesh
+// ignores the level costs, impassable tiles included, and the grid's step
+// and slope limits
+const PlayerNavMesh bool = false
+
+// Folder under the user cache directory the navigation meshes are baked to
+const NavMeshCacheDir string = "raylib-demo/navmesh"
+
+// Level file holding the terrain costs of the demo world
+const LevelFile string = "res/levels/demo.json"
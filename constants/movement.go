@@ -0,0 +1,12 @@
+package constants
+
+// Speed stats are tuned as distance per frame at this frame rate; steering
+// works in distance per second
+const StatSpeedScale float32 = 60
+
+// Path following: waypoint acceptance radius, slowdown distance before the
+// end of a path, look-ahead distance along it and the steering acceleration
+const FollowAcceptRadius float32 = 0.5
+const FollowArriveRadius float32 = 1.5
+const FollowLookAhead float32 = 1
+const FollowAcceleration float32 = 60
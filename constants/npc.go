@@ -0,0 +1,12 @@
+package constants
+
+import rl "github.com/gen2brain/raylib-go/raylib"
+
+// Patrolling NPCs: walking speed in units per second, the size and colour
+// of the body drawn for them and the path cache profile they share
+const NPCMoveSpeed float32 = 3
+const NPCRadius float32 = 0.5
+const NPCHeight float32 = 2
+const NPCProfile string = "npc"
+
+var NPCColor rl.Color = rl.Maroon
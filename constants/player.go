@@ -12,3 +12,6 @@ var Health = 100
 var Mana = 100
 var Speed = 5
 var MoveSpeed float32 = 0.2
+
+// Path cache profile shared by every player sized agent
+const PlayerProfile string = "player"
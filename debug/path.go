@@ -0,0 +1,187 @@
+package debug
+
+import (
+	cts "main/constants"
+	f "main/pathfinder"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// PathDebug draws what the pathfinder sees for an agent: its path, target,
+// the blocked cells of the grid and, once a search has been recorded, the
+// open and closed sets of that search at the step being scrubbed to.
+//
+// Keys: R records the agent's last query, Space plays or pauses the replay,
+// [ and ] scrub backwards and forwards, Home and End jump to either end.
+type PathDebug interface {
+	HandleInput(agent f.Agent)
+	DebugMode(mode bool, agent f.Agent) bool
+	GetRecording() *f.Recording
+	SetRecording(newRecording *f.Recording)
+	GetStep() int
+	SetStep(newStep int)
+}
+
+type pathDebug struct {
+	grid      f.NavGrid
+	recording *f.Recording
+	step      int
+	playing   bool
+	// open and closed hold the sets at setsStep, rebuilt when the step moves
+	open     map[f.Cell]f.SearchNode
+	closed   map[f.Cell]f.SearchNode
+	setsStep int
+	// minCost and maxCost span the f costs of the recording, for colouring
+	minCost float64
+	maxCost float64
+}
+
+// NewPathDebug creates a new instance of PathDebug drawing over grid
+func NewPathDebug(grid f.NavGrid) PathDebug {
+	return &pathDebug{
+		grid:     grid,
+		setsStep: -1,
+	}
+}
+
+// Getters and Setters for recording
+
+func (d *pathDebug) GetRecording() *f.Recording {
+	return d.recording
+}
+
+// SetRecording shows newRecording from its first step.
+func (d *pathDebug) SetRecording(newRecording *f.Recording) {
+	d.recording = newRecording
+	d.step = 0
+	d.setsStep = -1
+	if newRecording == nil {
+		return
+	}
+	d.minCost, d.maxCost = 0, 0
+	for i, step := range newRecording.Steps {
+		cost := step.Expanded.GCost + step.Expanded.HCost
+		if i == 0 || cost < d.minCost {
+			d.minCost = cost
+		}
+		d.maxCost = max(d.maxCost, cost)
+	}
+}
+
+// Getters and Setters for step
+
+func (d *pathDebug) GetStep() int {
+	return d.step
+}
+
+// SetStep moves the replay to newStep expansions, clamped to the recording.
+func (d *pathDebug) SetStep(newStep int) {
+	if d.recording == nil {
+		d.step = 0
+		return
+	}
+	d.step = min(max(newStep, 0), len(d.recording.Steps))
+}
+
+// HandleInput records and scrubs searches of agent.
+func (d *pathDebug) HandleInput(agent f.Agent) {
+	if rl.IsKeyPressed(rl.KeyR) {
+		d.SetRecording(f.RecordSearch(agent.GetGrid(), agent.GetCurrentPos(), agent.GetTargetPos(), agent.GetOptions()))
+		d.playing = true
+	}
+	if d.recording == nil {
+		return
+	}
+
+	if rl.IsKeyPressed(rl.KeySpace) {
+		d.playing = !d.playing
+	}
+	switch {
+	case rl.IsKeyDown(rl.KeyLeftBracket):
+		d.playing = false
+		d.SetStep(d.step - cts.DebugScrubSpeed)
+	case rl.IsKeyDown(rl.KeyRightBracket):
+		d.playing = false
+		d.SetStep(d.step + cts.DebugScrubSpeed)
+	case rl.IsKeyPressed(rl.KeyHome):
+		d.SetStep(0)
+	case rl.IsKeyPressed(rl.KeyEnd):
+		d.SetStep(len(d.recording.Steps))
+	case d.playing:
+		d.SetStep(d.step + cts.DebugPlaySpeed)
+		d.playing = d.step < len(d.recording.Steps)
+	}
+}
+
+// DebugMode draws the debug view of agent when mode is set. Call it
+// between BeginMode3D and EndMode3D.
+func (d *pathDebug) DebugMode(mode bool, agent f.Agent) bool {
+	if !mode {
+		return false
+	}
+	d.drawBlocked()
+	d.drawSearch()
+	drawPath(agent.GetPath(), rl.DarkGray)
+	rl.DrawSphere(agent.GetTargetPos(), 0.2, rl.Green)
+	return true
+}
+
+// drawBlocked outlines every blocked cell of the grid.
+func (d *pathDebug) drawBlocked() {
+	size := d.grid.GetSize()
+	cellSize := d.grid.GetCellSize()
+	for x := 0; x < size.X; x++ {
+		for y := 0; y < size.Y; y++ {
+			for z := 0; z < size.Z; z++ {
+				cell := f.Cell{X: x, Y: y, Z: z}
+				if d.grid.IsBlocked(cell) {
+					rl.DrawCubeWires(d.grid.CellToWorld(cell), cellSize, cellSize, cellSize, rl.Gray)
+				}
+			}
+		}
+	}
+}
+
+// drawSearch draws the recorded open and closed sets at the current step,
+// coloured from green for the cheapest f cost to red for the dearest.
+func (d *pathDebug) drawSearch() {
+	if d.recording == nil {
+		return
+	}
+	if d.setsStep != d.step {
+		d.open, d.closed = d.recording.SetsAt(d.step)
+		d.setsStep = d.step
+	}
+
+	size := d.grid.GetCellSize() / 2
+	for _, node := range d.closed {
+		rl.DrawCube(node.Position, size, size, size, d.costColor(node))
+	}
+	for _, node := range d.open {
+		rl.DrawCubeWires(node.Position, size, size, size, d.costColor(node))
+	}
+	if d.step > 0 {
+		current := d.recording.Steps[d.step-1].Expanded
+		rl.DrawCubeWires(current.Position, size*1.5, size*1.5, size*1.5, rl.Yellow)
+	}
+	if d.step == len(d.recording.Steps) {
+		drawPath(d.recording.Result.Path, rl.Blue)
+	}
+	rl.DrawSphere(d.recording.Target, 0.15, rl.Lime)
+}
+
+func (d *pathDebug) costColor(node f.SearchNode) rl.Color {
+	t := float32(0)
+	if d.maxCost > d.minCost {
+		t = float32((node.GCost + node.HCost - d.minCost) / (d.maxCost - d.minCost))
+	}
+	t = rl.Clamp(t, 0, 1)
+	return rl.NewColor(uint8(255*t), uint8(255*(1-t)), 0, 255)
+}
+
+// drawPath renders path as a series of connected lines.
+func drawPath(path []rl.Vector3, color rl.Color) {
+	for i := 0; i < len(path)-1; i++ {
+		rl.DrawLine3D(path[i], path[i+1], color)
+	}
+}
@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"context"
+	cts "main/constants"
+	"main/movement"
+	f "main/pathfinder"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// navigator walks an entity through the destinations of a patrol. It asks
+// pathService for the path to each destination and steers along it.
+type navigator struct {
+	agent f.Agent
+	// pathService answers the agent's path queries off the game loop
+	pathService f.Service
+	follower    movement.PathFollower
+	patrol      movement.Patrol
+}
+
+func newNavigator(agent f.Agent, pathService f.Service) *navigator {
+	return &navigator{
+		agent:       agent,
+		pathService: pathService,
+		follower: movement.NewPathFollower(movement.FollowerConfig{
+			AcceptRadius: cts.FollowAcceptRadius,
+			ArriveRadius: cts.FollowArriveRadius,
+			LookAhead:    cts.FollowLookAhead,
+			Acceleration: cts.FollowAcceleration,
+		}),
+		patrol: movement.NewPatrol(movement.PatrolRoute{}),
+	}
+}
+
+// update moves pos along the route for dt seconds at speed units per second
+// and returns the new position.
+func (n *navigator) update(pos rl.Vector3, speed, dt float32) rl.Vector3 {
+	arrived := !n.agent.IsPending() && n.follower.IsDone()
+	if target, ok := n.patrol.Update(arrived, dt); ok {
+		n.findPath(pos, target)
+	}
+	if n.follower.IsDone() {
+		return pos
+	}
+	pos = n.follower.Update(pos, speed, dt)
+	n.agent.SetCurrentPos(pos)
+	n.agent.SetPath(n.follower.GetPath())
+	return pos
+}
+
+// findPath queries the path from pos to target and hands it to the follower
+// once it is found. The agent drops the answers to older queries, and an
+// unreachable target leaves the follower without a path.
+func (n *navigator) findPath(pos, target rl.Vector3) {
+	n.agent.SetCurrentPos(pos)
+	ctx, cancel := context.WithTimeout(context.Background(), cts.PathTimeout)
+	err := n.agent.FindPathAsync(ctx, n.pathService, target, func(result f.Result) {
+		cancel()
+		if result.Status != f.StatusCanceled {
+			n.follower.SetPath(result.Path)
+		}
+	})
+	if err != nil {
+		cancel()
+	}
+}
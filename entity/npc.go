@@ -0,0 +1,76 @@
+package entity
+
+import (
+	cts "main/constants"
+	"main/movement"
+	f "main/pathfinder"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type NPC interface {
+	Process()
+	DebugMode(mode bool) bool
+	GetAgent() f.Agent
+	FollowRoute(route movement.PatrolRoute)
+}
+
+type npc struct {
+	position rl.Vector3
+	// navigator walks the NPC along its patrol route
+	navigator *navigator
+}
+
+// NewNPC creates a new instance of NPC standing at the first point of route and
+// patrolling it on navGrid, routed like NewPlayer
+func NewNPC(navGrid f.NavGrid, pathService f.Service, pathCache f.PathCache, costMap f.CostMap, route movement.PatrolRoute) NPC {
+	var position rl.Vector3
+	if len(route.Points) > 0 {
+		position = route.Points[0].Position
+	}
+	agent := f.NewAgent(navGrid, position, cts.NPCMoveSpeed)
+	options := agent.GetOptions()
+	options.CostMap = costMap
+	options.Profile = cts.NPCProfile
+	options.AgentRadius = cts.NPCRadius
+	agent.SetOptions(options)
+	agent.SetCache(pathCache)
+
+	n := &npc{
+		position:  position,
+		navigator: newNavigator(agent, pathService),
+	}
+	n.FollowRoute(route)
+	return n
+}
+
+// Process walks the NPC along its route for the frame and draws it
+func (n *npc) Process() {
+	n.position = n.navigator.update(n.position, cts.NPCMoveSpeed, rl.GetFrameTime())
+	rl.DrawCylinder(n.position, cts.NPCRadius, cts.NPCRadius, cts.NPCHeight, 8, cts.NPCColor)
+}
+
+// DebugMode draws the NPC's patrol route
+func (n *npc) DebugMode(mode bool) bool {
+	if mode {
+		points := n.navigator.patrol.GetRoute().Points
+		for i, point := range points {
+			rl.DrawSphere(point.Position, 0.2, cts.NPCColor)
+			if i > 0 {
+				rl.DrawLine3D(points[i-1].Position, point.Position, cts.NPCColor)
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// GetAgent returns the agent holding the NPC's path state
+func (n *npc) GetAgent() f.Agent {
+	return n.navigator.agent
+}
+
+// FollowRoute makes the NPC walk route from its first point
+func (n *npc) FollowRoute(route movement.PatrolRoute) {
+	n.navigator.patrol.SetRoute(route)
+}
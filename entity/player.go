@@ -20,8 +20,8 @@ type Player interface {
 	KeyboardMovement()
 	MouseMovement(camera rl.Camera)
 	DebugMode(mode bool) bool
-	moveObjectAlongPath(direction rl.Vector3)
-	moveAlongPath()
+	GetAgent() f.Agent
+	FollowRoute(route movement.PatrolRoute)
 	CleanUp()
 	HandleCollison(obj rl.BoundingBox)
 }
@@ -30,14 +30,27 @@ type player struct {
 	model  model.BaseModel
 	stat   stats.StaticStat
 	hitBox collision.HitBox
+	// navigator walks the player through the destinations clicked
+	navigator *navigator
 }
 
-// NewPlayer creates a new instance of Player with initial values
-func NewPlayer() Player {
+// NewPlayer creates a new instance of Player with initial values that routes on navGrid
+// through pathService and pathCache, preferring cheap terrain in costMap when it is not nil
+func NewPlayer(navGrid f.NavGrid, pathService f.Service, pathCache f.PathCache, costMap f.CostMap) Player {
+	agent := f.NewAgent(navGrid, cts.Position, cts.MoveSpeed)
+	options := agent.GetOptions()
+	options.CostMap = costMap
+	options.Profile = cts.PlayerProfile
+	options.AgentRadius = cts.NavAgentRadius
+	agent.SetOptions(options)
+	agent.SetCache(pathCache)
+
 	return &player{
 		model:  model.NewBaseModel(cts.ModelPath, cts.TexturePath, cts.Position, cts.Scale),
 		stat:   stats.NewStaticStat(cts.Health, cts.Mana, cts.MoveSpeed),
 		hitBox: collision.NewHitBox(cts.Vec3Zero, cts.Vec3Zero),
+
+		navigator: newNavigator(agent, pathService),
 	}
 }
 
@@ -56,13 +69,16 @@ func (p *player) MouseMovement(camera rl.Camera) {
 	if rl.IsMouseButtonPressed(rl.MouseRightButton) {
 		picker := picker.Process(camera, g0, g1, g2, g3)
 		if picker.Hit {
-			f.SetTargetPos(picker.Point)
-			f.FindPath(f.GetTargetPos())
+			// Shift queues the destination after the ones already clicked
+			point := movement.PatrolPoint{Position: picker.Point}
+			if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
+				p.navigator.patrol.Append(point)
+			} else {
+				p.navigator.patrol.SetRoute(movement.PatrolRoute{Points: []movement.PatrolPoint{point}})
+			}
 		}
 	}
-	if len(f.GetPath()) > 0 {
-		p.moveAlongPath()
-	}
+	p.followPath(rl.GetFrameTime())
 }
 
 func (p *player) KeyboardMovement() {
@@ -70,35 +86,14 @@ func (p *player) KeyboardMovement() {
 	p.model.SetPosition(movement.HandleMovement(p.model.GetPosition(),p.stat.GetSpeed()))
 }
 
-// moveAlongPath moves the player along the calculated path.
-func (p *player) moveAlongPath() {
-	direction := rl.Vector3Subtract(f.GetPath()[0], f.GetcurrentPos())
-	distance := rl.Vector3Length(direction)
-
-	if distance > f.GetMoveSpeed() {
-		p.moveObjectAlongPath(direction)
-	} else {
-		f.SetPath(f.GetPath()[1:])
-	}
+// followPath steers the player through the queued destinations for dt seconds.
+func (p *player) followPath(dt float32) {
+	p.model.SetPosition(p.navigator.update(p.model.GetPosition(), p.stat.GetSpeed()*cts.StatSpeedScale, dt))
 }
 
-func (p *player) moveObjectAlongPath(direction rl.Vector3) {
-	direction = rl.Vector3Normalize(direction)
-	f.SetcurrentPos(rl.Vector3Add(f.GetcurrentPos(), rl.Vector3Scale(direction, f.GetMoveSpeed())))
-
-	// Smoothly interpolate between path points for smoother movement
-	if len(f.GetPath()) > 1 {
-		directionToNextPoint := rl.Vector3Subtract(f.GetPath()[0], f.GetcurrentPos())
-		directionToNextPoint = rl.Vector3Normalize(directionToNextPoint)
-		f.SetcurrentPos(rl.Vector3Add(f.GetcurrentPos(), rl.Vector3Scale(directionToNextPoint, f.GetMoveSpeed())))
-
-		// Check if reached the next point
-		distanceToNextPoint := rl.Vector3Distance(f.GetcurrentPos(), f.GetPath()[0])
-		if distanceToNextPoint < f.GetMoveSpeed() {
-			f.SetPath(f.GetPath()[1:])
-		}
-	}
-	p.model.SetPosition(f.GetcurrentPos())
+// FollowRoute makes the player walk route, replacing the clicked destinations
+func (p *player) FollowRoute(route movement.PatrolRoute) {
+	p.navigator.patrol.SetRoute(route)
 }
 
 func (p *player) DebugMode(mode bool) bool {
@@ -113,6 +108,11 @@ func (p *player) DebugMode(mode bool) bool {
 	return false
 }
 
+// GetAgent returns the agent holding the player's path state
+func (p *player) GetAgent() f.Agent {
+	return p.navigator.agent
+}
+
 func (p *player) CleanUp() {
 	p.model.CleanUp(p.model.GetModel(), p.model.GetTexture())
 }
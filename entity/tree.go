@@ -24,11 +24,13 @@ type tree struct {
 }
 
 func NewTree() Tree{
-return &tree{
+  t := &tree{
     model: model.NewBaseModel(cts.TreeModel,cts.TreeTexture,cts.TreePos,1),
     stat: stats.NewStaticStat(cts.Health, 0,0),
     hitBox: collision.NewHitBox(cts.Vec3Zero, cts.Vec3Zero),
   }
+  t.updateHitBox()
+  return t
 }
 
 func(p *tree) Process(){
@@ -37,9 +39,7 @@ func(p *tree) Process(){
 
 func (p *tree) DebugMode(mode bool) bool {
 	if mode {
-		min := rl.NewVector3(p.model.GetPosition().X-1, p.model.GetPosition().Y, p.model.GetPosition().Z-1)
-		max := rl.NewVector3(p.model.GetPosition().X+1, p.model.GetPosition().Y+2, p.model.GetPosition().Z+1)
-		p.hitBox.SetHitBox(min, max)
+		p.updateHitBox()
     
 		rl.DrawBoundingBox(p.hitBox.GetHitBox(), rl.Green)
 		return true
@@ -51,6 +51,13 @@ func (p *tree) CleanUp(){
   p.model.CleanUp(p.model.GetModel(), p.model.GetTexture())
 }
 
+// updateHitBox fits the hit box around the tree's current position
+func (p *tree) updateHitBox() {
+	min := rl.NewVector3(p.model.GetPosition().X-1, p.model.GetPosition().Y, p.model.GetPosition().Z-1)
+	max := rl.NewVector3(p.model.GetPosition().X+1, p.model.GetPosition().Y+2, p.model.GetPosition().Z+1)
+	p.hitBox.SetHitBox(min, max)
+}
+
 func(p *tree) GetHitBox()rl.BoundingBox{
   return p.hitBox.GetHitBox()
 }
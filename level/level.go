@@ -0,0 +1,124 @@
+package level
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"main/movement"
+	f "main/pathfinder"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Level is the content of a level file.
+type Level struct {
+	Name  string     `json:"name"`
+	Costs *CostLayer `json:"costs,omitempty"`
+	// Patrols are the routes walked by the NPCs of the level
+	Patrols []Patrol `json:"patrols,omitempty"`
+	// dir is the folder of the level file, paths inside it are relative to it
+	dir string
+}
+
+// CostLayer describes the terrain cost map of a level, either as rows of
+// legend characters or as a grayscale image.
+type CostLayer struct {
+	// Scale is the number of grid cells covered by each character or pixel
+	Scale int `json:"scale"`
+	// Legend maps row characters to cost multipliers, negative is impassable
+	Legend map[string]float32 `json:"legend,omitempty"`
+	Rows   []string           `json:"rows,omitempty"`
+	// Image is a grayscale cost map used instead of Rows, see pathfinder.NewCostMapFromImage
+	Image   string  `json:"image,omitempty"`
+	MaxCost float32 `json:"maxCost,omitempty"`
+}
+
+// Patrol is a patrol route walked by an NPC.
+type Patrol struct {
+	Name string `json:"name"`
+	// Mode is "once", "loop" or "pingpong", see movement.PatrolMode
+	Mode   string        `json:"mode"`
+	Points []PatrolPoint `json:"points"`
+}
+
+// PatrolPoint is a stop of a patrol route.
+type PatrolPoint struct {
+	Position [3]float32 `json:"position"`
+	// Wait is the number of seconds spent at the point
+	Wait float32 `json:"wait,omitempty"`
+}
+
+// Load reads the level file at path.
+func Load(path string) (*Level, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	l := &Level{dir: filepath.Dir(path)}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("level: decode %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// CostMap builds the level's terrain cost map, nil when it has none.
+func (l *Level) CostMap() (f.CostMap, error) {
+	if l.Costs == nil {
+		return nil, nil
+	}
+	c := l.Costs
+	if c.Image != "" {
+		return f.LoadCostMapImage(filepath.Join(l.dir, c.Image), c.MaxCost, c.Scale)
+	}
+
+	columns := 0
+	for _, row := range c.Rows {
+		columns = max(columns, len(row))
+	}
+	costMap := f.NewCostMap(columns, len(c.Rows), c.Scale)
+	for z, row := range c.Rows {
+		for x, char := range []byte(row) {
+			cost, ok := c.Legend[string(char)]
+			if !ok {
+				return nil, fmt.Errorf("level %s: cost row %d uses %q missing from the legend", l.Name, z, char)
+			}
+			costMap.SetCost(x, z, cost)
+		}
+	}
+	return costMap, nil
+}
+
+// Save writes the level to path.
+func (l *Level) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// NewPatrol describes route under name, ready to be saved in a level.
+func NewPatrol(name string, route movement.PatrolRoute) Patrol {
+	p := Patrol{Name: name, Mode: route.Mode.String()}
+	for _, point := range route.Points {
+		position := [3]float32{point.Position.X, point.Position.Y, point.Position.Z}
+		p.Points = append(p.Points, PatrolPoint{Position: position, Wait: point.Wait})
+	}
+	return p
+}
+
+// Route returns the patrol as a route entities can follow.
+func (p Patrol) Route() (movement.PatrolRoute, error) {
+	mode, err := movement.ParsePatrolMode(p.Mode)
+	if err != nil {
+		return movement.PatrolRoute{}, fmt.Errorf("level patrol %s: %w", p.Name, err)
+	}
+	route := movement.PatrolRoute{Mode: mode}
+	for _, point := range p.Points {
+		position := rl.NewVector3(point.Position[0], point.Position[1], point.Position[2])
+		route.Points = append(route.Points, movement.PatrolPoint{Position: position, Wait: point.Wait})
+	}
+	return route, nil
+}
@@ -0,0 +1,175 @@
+package movement
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// minArriveFactor keeps the follower moving while it slows down to arrive,
+// so it reaches the last waypoint in finite time.
+const minArriveFactor = 0.1
+
+// FollowerConfig tunes how a PathFollower steers.
+type FollowerConfig struct {
+	// AcceptRadius is how close a waypoint must be before steering moves on to the next one
+	AcceptRadius float32
+	// ArriveRadius is the distance left on the path where the follower starts slowing down
+	ArriveRadius float32
+	// LookAhead is how far along the path the follower steers toward, 0 seeks the next waypoint
+	LookAhead float32
+	// Acceleration limits how fast the velocity turns and changes speed, 0 turns instantly
+	Acceleration float32
+}
+
+// PathFollower steers an entity along a path of waypoints. It seeks a point
+// LookAhead ahead on the path, skips waypoints within AcceptRadius and slows
+// down over ArriveRadius before the end.
+type PathFollower interface {
+	GetConfig() FollowerConfig
+	SetConfig(newConfig FollowerConfig)
+	GetPath() []rl.Vector3
+	SetPath(newPath []rl.Vector3)
+	GetVelocity() rl.Vector3
+	IsDone() bool
+	Update(pos rl.Vector3, speed, dt float32) rl.Vector3
+	Stop()
+}
+
+type pathFollower struct {
+	config FollowerConfig
+	// path holds the waypoints left, anchor is where the segment to path[0] starts
+	path     []rl.Vector3
+	anchor   rl.Vector3
+	anchored bool
+	velocity rl.Vector3
+}
+
+// NewPathFollower creates a new instance of PathFollower with no path
+func NewPathFollower(config FollowerConfig) PathFollower {
+	return &pathFollower{
+		config: config,
+	}
+}
+
+// Getters and Setters for config
+
+func (f *pathFollower) GetConfig() FollowerConfig {
+	return f.config
+}
+
+func (f *pathFollower) SetConfig(newConfig FollowerConfig) {
+	f.config = newConfig
+}
+
+// Getters and Setters for path
+
+// GetPath returns the waypoints not reached yet.
+func (f *pathFollower) GetPath() []rl.Vector3 {
+	return f.path
+}
+
+// SetPath replaces the path, keeping the current velocity so the follower
+// turns smoothly onto the new one.
+func (f *pathFollower) SetPath(newPath []rl.Vector3) {
+	f.path = newPath
+	f.anchored = false
+}
+
+func (f *pathFollower) GetVelocity() rl.Vector3 {
+	return f.velocity
+}
+
+// IsDone reports whether the follower reached the end of its path.
+func (f *pathFollower) IsDone() bool {
+	return len(f.path) == 0
+}
+
+// Stop drops the path and halts at once.
+func (f *pathFollower) Stop() {
+	f.path = nil
+	f.velocity = rl.Vector3{}
+}
+
+// Update moves pos along the path for dt seconds at up to speed units per
+// second and returns the new position.
+func (f *pathFollower) Update(pos rl.Vector3, speed, dt float32) rl.Vector3 {
+	if len(f.path) == 0 {
+		f.velocity = rl.Vector3{}
+		return pos
+	}
+	if !f.anchored {
+		f.anchor = pos
+		f.anchored = true
+	}
+	// A waypoint within a single step counts as reached too, or a small
+	// radius would make the follower circle around it
+	accept := max(f.config.AcceptRadius, speed*dt)
+	for len(f.path) > 1 && rl.Vector3Distance(pos, f.path[0]) <= accept {
+		f.anchor = f.path[0]
+		f.path = f.path[1:]
+	}
+
+	// Arrive: ease off over the last stretch of the path
+	desiredSpeed := speed
+	if remaining := f.remaining(pos); f.config.ArriveRadius > 0 && remaining < f.config.ArriveRadius {
+		desiredSpeed = speed * max(remaining/f.config.ArriveRadius, minArriveFactor)
+	}
+
+	// Seek the look-ahead point
+	desired := rl.Vector3Subtract(f.lookAhead(pos), pos)
+	if length := rl.Vector3Length(desired); length > 0 {
+		desired = rl.Vector3Scale(desired, desiredSpeed/length)
+	}
+	if f.config.Acceleration > 0 {
+		steering := rl.Vector3Subtract(desired, f.velocity)
+		if length, limit := rl.Vector3Length(steering), f.config.Acceleration*dt; length > limit {
+			steering = rl.Vector3Scale(steering, limit/length)
+		}
+		f.velocity = rl.Vector3Add(f.velocity, steering)
+	} else {
+		f.velocity = desired
+	}
+
+	// Land on the last waypoint instead of overshooting it
+	end := f.path[len(f.path)-1]
+	step := rl.Vector3Length(f.velocity) * dt
+	if len(f.path) == 1 && rl.Vector3Distance(pos, end) <= max(step, desiredSpeed*dt) {
+		f.Stop()
+		return end
+	}
+	return rl.Vector3Add(pos, rl.Vector3Scale(f.velocity, dt))
+}
+
+// remaining returns the distance left from pos to the end of the path.
+func (f *pathFollower) remaining(pos rl.Vector3) float32 {
+	distance := rl.Vector3Distance(pos, f.path[0])
+	for i := 1; i < len(f.path); i++ {
+		distance += rl.Vector3Distance(f.path[i-1], f.path[i])
+	}
+	return distance
+}
+
+// lookAhead projects pos onto the current segment and walks LookAhead
+// further along the path from there.
+func (f *pathFollower) lookAhead(pos rl.Vector3) rl.Vector3 {
+	if f.config.LookAhead <= 0 {
+		return f.path[0]
+	}
+	from := f.anchor
+	segment := rl.Vector3Subtract(f.path[0], from)
+	point := f.path[0]
+	if length := rl.Vector3LengthSqr(segment); length > 0 {
+		t := rl.Vector3DotProduct(rl.Vector3Subtract(pos, from), segment) / length
+		point = rl.Vector3Lerp(from, f.path[0], rl.Clamp(t, 0, 1))
+	}
+
+	left := f.config.LookAhead
+	for i := 0; i < len(f.path); i++ {
+		distance := rl.Vector3Distance(point, f.path[i])
+		if distance >= left && distance > 0 {
+			return rl.Vector3Lerp(point, f.path[i], left/distance)
+		}
+		left -= distance
+		point = f.path[i]
+	}
+	return point
+}
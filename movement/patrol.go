@@ -0,0 +1,185 @@
+package movement
+
+import (
+	"fmt"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// PatrolMode decides where a patrol goes after its last point.
+type PatrolMode int
+
+const (
+	// PatrolOnce stops at the last point, which makes the route a queue of destinations
+	PatrolOnce PatrolMode = iota
+	// PatrolLoop heads back to the first point
+	PatrolLoop
+	// PatrolPingPong walks the points back in reverse order
+	PatrolPingPong
+)
+
+func (m PatrolMode) String() string {
+	switch m {
+	case PatrolLoop:
+		return "loop"
+	case PatrolPingPong:
+		return "pingpong"
+	default:
+		return "once"
+	}
+}
+
+// ParsePatrolMode returns the mode named name, as written by String.
+func ParsePatrolMode(name string) (PatrolMode, error) {
+	for _, mode := range []PatrolMode{PatrolOnce, PatrolLoop, PatrolPingPong} {
+		if mode.String() == name {
+			return mode, nil
+		}
+	}
+	return PatrolOnce, fmt.Errorf("movement: unknown patrol mode %q", name)
+}
+
+// PatrolPoint is a destination of a patrol route.
+type PatrolPoint struct {
+	Position rl.Vector3
+	// Wait is the number of seconds spent at the point before moving on
+	Wait float32
+}
+
+// PatrolRoute is an ordered list of points walked in Mode.
+type PatrolRoute struct {
+	Points []PatrolPoint
+	Mode   PatrolMode
+}
+
+// Patrol hands out the destinations of a route one at a time. It does not
+// move anything itself: the entity following it travels to each target,
+// for instance with a PathFollower, and reports when it got there.
+type Patrol interface {
+	GetRoute() PatrolRoute
+	SetRoute(newRoute PatrolRoute)
+	Append(point PatrolPoint)
+	GetIndex() int
+	GetTarget() (rl.Vector3, bool)
+	IsWaiting() bool
+	IsDone() bool
+	Update(arrived bool, dt float32) (rl.Vector3, bool)
+}
+
+type patrol struct {
+	route PatrolRoute
+	// index is the point being walked to, len(route.Points) once done
+	index int
+	// step is +1 or -1, the direction a ping pong route is walked in
+	step int
+	// issued is set once the current target was handed out
+	issued bool
+	waited float32
+}
+
+// NewPatrol creates a new instance of Patrol following route from its first point
+func NewPatrol(route PatrolRoute) Patrol {
+	p := &patrol{}
+	p.SetRoute(route)
+	return p
+}
+
+// Getters and Setters for route
+
+func (p *patrol) GetRoute() PatrolRoute {
+	return p.route
+}
+
+// SetRoute replaces the route and starts over from its first point.
+func (p *patrol) SetRoute(newRoute PatrolRoute) {
+	p.route = newRoute
+	p.index = 0
+	p.step = 1
+	p.issued = false
+	p.waited = 0
+}
+
+// Append adds point to the end of the route. A finished route resumes with
+// the new point.
+func (p *patrol) Append(point PatrolPoint) {
+	done := p.IsDone()
+	p.route.Points = append(p.route.Points, point)
+	if done {
+		p.index = len(p.route.Points) - 1
+		p.issued = false
+		p.waited = 0
+	}
+}
+
+// GetIndex returns the index of the point being walked to.
+func (p *patrol) GetIndex() int {
+	return p.index
+}
+
+// GetTarget returns the point being walked to, false once the route is done.
+func (p *patrol) GetTarget() (rl.Vector3, bool) {
+	if p.IsDone() {
+		return rl.Vector3{}, false
+	}
+	return p.route.Points[p.index].Position, true
+}
+
+// IsWaiting reports whether the follower stands at a point waiting to move on.
+func (p *patrol) IsWaiting() bool {
+	return p.waited > 0
+}
+
+// IsDone reports whether a PatrolOnce route reached its last point.
+func (p *patrol) IsDone() bool {
+	return p.index >= len(p.route.Points)
+}
+
+// Update advances the patrol by dt seconds. arrived tells whether the
+// follower reached the current target. It returns the next target to travel
+// to, and true, whenever the follower should set off toward a new one.
+func (p *patrol) Update(arrived bool, dt float32) (rl.Vector3, bool) {
+	if p.IsDone() {
+		return rl.Vector3{}, false
+	}
+	if !p.issued {
+		p.issued = true
+		return p.route.Points[p.index].Position, true
+	}
+	if !arrived {
+		return rl.Vector3{}, false
+	}
+
+	p.waited += dt
+	if p.waited < p.route.Points[p.index].Wait {
+		return rl.Vector3{}, false
+	}
+	p.waited = 0
+	next := p.next()
+	if next >= len(p.route.Points) || next == p.index {
+		// The last point of a PatrolOnce route, or the only point of any route
+		p.index = len(p.route.Points)
+		return rl.Vector3{}, false
+	}
+	p.index = next
+	return p.route.Points[p.index].Position, true
+}
+
+// next returns the index of the point after the current one, turning the
+// step around at the ends of a ping pong route.
+func (p *patrol) next() int {
+	count := len(p.route.Points)
+	switch p.route.Mode {
+	case PatrolLoop:
+		return (p.index + 1) % count
+	case PatrolPingPong:
+		if count < 2 {
+			return p.index
+		}
+		if next := p.index + p.step; next < 0 || next >= count {
+			p.step = -p.step
+		}
+		return p.index + p.step
+	default:
+		return p.index + 1
+	}
+}
@@ -0,0 +1,212 @@
+package pathfinder
+
+import (
+	"context"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Agent owns the path state of a single moving entity, so any number of
+// entities can route at the same time.
+type Agent interface {
+	GetGrid() NavGrid
+	SetGrid(newGrid NavGrid)
+	GetOptions() Options
+	SetOptions(newOptions Options)
+	GetPath() []rl.Vector3
+	SetPath(newPath []rl.Vector3)
+	GetTargetPos() rl.Vector3
+	SetTargetPos(newTargetPos rl.Vector3)
+	GetCurrentPos() rl.Vector3
+	SetCurrentPos(newCurrentPos rl.Vector3)
+	GetMoveSpeed() float32
+	SetMoveSpeed(newMoveSpeed float32)
+	GetCache() PathCache
+	SetCache(newCache PathCache)
+	GetNavMesh() NavMesh
+	SetNavMesh(newNavMesh NavMesh)
+	FindPath(target rl.Vector3) Result
+	FindPathAsync(ctx context.Context, service Service, target rl.Vector3, done func(Result)) error
+	IsPending() bool
+}
+
+type agent struct {
+	grid       NavGrid
+	options    Options
+	path       []rl.Vector3
+	targetPos  rl.Vector3
+	currentPos rl.Vector3
+	moveSpeed  float32
+	// cache, when not nil, answers queries instead of searching grid. It
+	// must have been created for grid
+	cache PathCache
+	// navMesh, when not nil, answers queries instead of grid and cache. The
+	// mesh only knows the obstacles it was built around, so options.CostMap,
+	// AgentRadius and the step and slope limits do not apply to it
+	navMesh NavMesh
+	// pending identifies the latest asynchronous request; older results are
+	// dropped. cancel is set until the latest request gets its result
+	pending uint64
+	cancel  context.CancelFunc
+}
+
+// NewAgent creates a new instance of Agent standing at currentPos that routes on grid
+func NewAgent(grid NavGrid, currentPos rl.Vector3, moveSpeed float32) Agent {
+	return &agent{
+		grid:       grid,
+		options:    DefaultOptions(),
+		targetPos:  currentPos,
+		currentPos: currentPos,
+		moveSpeed:  moveSpeed,
+	}
+}
+
+// Getters and Setters for grid
+
+func (a *agent) GetGrid() NavGrid {
+	return a.grid
+}
+
+func (a *agent) SetGrid(newGrid NavGrid) {
+	a.grid = newGrid
+}
+
+// Getters and Setters for options
+
+func (a *agent) GetOptions() Options {
+	return a.options
+}
+
+func (a *agent) SetOptions(newOptions Options) {
+	a.options = newOptions
+}
+
+// Getters and Setters for path
+
+func (a *agent) GetPath() []rl.Vector3 {
+	return a.path
+}
+
+func (a *agent) SetPath(newPath []rl.Vector3) {
+	a.path = newPath
+}
+
+// Getters and Setters for targetPos
+
+func (a *agent) GetTargetPos() rl.Vector3 {
+	return a.targetPos
+}
+
+func (a *agent) SetTargetPos(newTargetPos rl.Vector3) {
+	a.targetPos = newTargetPos
+}
+
+// Getters and Setters for currentPos
+
+func (a *agent) GetCurrentPos() rl.Vector3 {
+	return a.currentPos
+}
+
+func (a *agent) SetCurrentPos(newCurrentPos rl.Vector3) {
+	a.currentPos = newCurrentPos
+}
+
+// Getters and Setters for moveSpeed
+
+func (a *agent) GetMoveSpeed() float32 {
+	return a.moveSpeed
+}
+
+func (a *agent) SetMoveSpeed(newMoveSpeed float32) {
+	a.moveSpeed = newMoveSpeed
+}
+
+// Getters and Setters for cache
+
+func (a *agent) GetCache() PathCache {
+	return a.cache
+}
+
+func (a *agent) SetCache(newCache PathCache) {
+	a.cache = newCache
+}
+
+// Getters and Setters for navMesh
+
+func (a *agent) GetNavMesh() NavMesh {
+	return a.navMesh
+}
+
+func (a *agent) SetNavMesh(newNavMesh NavMesh) {
+	a.navMesh = newNavMesh
+}
+
+// FindPath computes a path from the agent's current position to target.
+// The agent follows whatever path the result holds, which is empty when the
+// target is unreachable. A pending asynchronous request is canceled and its
+// result dropped, so it cannot replace the new path.
+func (a *agent) FindPath(target rl.Vector3) Result {
+	if a.cancel != nil {
+		a.cancel()
+		a.cancel = nil
+	}
+	a.pending++
+	a.targetPos = target
+	var result Result
+	if a.navMesh != nil {
+		result = a.navMesh.FindPath(a.currentPos, target)
+	} else if a.cache != nil {
+		result = findPathCached(context.Background(), a.cache, a.grid, a.currentPos, target, a.options)
+	} else {
+		result = FindPath(a.grid, a.currentPos, target, a.options)
+	}
+	a.path = result.Path
+	return result
+}
+
+// IsPending reports whether the latest asynchronous request still waits for
+// its result.
+func (a *agent) IsPending() bool {
+	return a.cancel != nil
+}
+
+// FindPathAsync submits a path query for target to service and keeps the
+// current path until the result is dispatched. A newer request cancels the
+// previous one. done, when not nil, is called after the path has been applied.
+func (a *agent) FindPathAsync(ctx context.Context, service Service, target rl.Vector3, done func(Result)) error {
+	if a.cancel != nil {
+		a.cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	a.pending++
+	a.cancel = cancel
+	id := a.pending
+
+	err := service.Submit(ctx, Request{
+		Grid:    a.grid,
+		Start:   a.currentPos,
+		Target:  target,
+		Options: a.options,
+		Cache:   a.cache,
+		NavMesh: a.navMesh,
+		Done: func(result Result) {
+			cancel()
+			if id != a.pending {
+				return
+			}
+			a.cancel = nil
+			a.targetPos = target
+			if result.Status != StatusCanceled {
+				a.path = result.Path
+			}
+			if done != nil {
+				done(result)
+			}
+		},
+	})
+	if err != nil {
+		cancel()
+		a.cancel = nil
+	}
+	return err
+}
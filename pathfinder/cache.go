@@ -0,0 +1,296 @@
+package pathfinder
+
+import (
+	"container/list"
+	"context"
+	"reflect"
+	"sync"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// CacheStats counts how a PathCache answered its queries.
+type CacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	Invalidations uint64
+	Evictions     uint64
+}
+
+// PathCache remembers the most recently used paths of a grid, keyed by the
+// start and goal cells, the agent profile and every option shaping the
+// path: agent size, height limits, cost map and search settings. A cached
+// path is dropped as soon as the grid changes near any of its segments, so
+// a hit is always walkable, though a change elsewhere may have opened a
+// shorter way.
+//
+// Only found paths are cached. A step cost function cannot be told apart
+// from another, so queries with one are only cached under a profile naming
+// it. A PathCache only answers for the grid it was created with; agents and
+// requests on another grid are rejected with ErrCacheGrid. A PathCache is
+// safe for concurrent use.
+type PathCache interface {
+	GetGrid() NavGrid
+	FindPath(start, target rl.Vector3, opts Options) Result
+	FindPathContext(ctx context.Context, start, target rl.Vector3, opts Options) Result
+	Invalidate(min, max Cell)
+	Clear()
+	GetStats() CacheStats
+	Len() int
+	Close()
+}
+
+type cacheKey struct {
+	profile string
+	start   Cell
+	target  Cell
+	shape   pathShape
+}
+
+// pathShape holds the options that change which path a query returns.
+type pathShape struct {
+	algorithm     Algorithm
+	mode          Mode
+	connectivity  Connectivity
+	corners       CornerRule
+	heuristic     reflect.Type
+	weight        float64
+	costMap       CostMap
+	maxStepHeight float32
+	maxSlope      float32
+	climbCost     float64
+	agentRadius   float32
+	stringPull    bool
+	curve         Curve
+	curveSegments int
+}
+
+func newPathShape(opts Options) pathShape {
+	return pathShape{
+		algorithm:     opts.Algorithm,
+		mode:          opts.Mode,
+		connectivity:  opts.Connectivity,
+		corners:       opts.Corners,
+		heuristic:     reflect.TypeOf(opts.Heuristic),
+		weight:        opts.Weight,
+		costMap:       opts.CostMap,
+		maxStepHeight: opts.MaxStepHeight,
+		maxSlope:      opts.MaxSlope,
+		climbCost:     opts.ClimbCost,
+		agentRadius:   opts.AgentRadius,
+		stringPull:    opts.StringPull,
+		curve:         opts.Curve,
+		curveSegments: opts.CurveSegments,
+	}
+}
+
+type cacheEntry struct {
+	key    cacheKey
+	result Result
+}
+
+type pathCache struct {
+	grid     NavGrid
+	capacity int
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	// order keeps the most recently used entry at the front
+	order *list.List
+	// generation counts invalidations, so a search that raced one is not stored
+	generation uint64
+	// reach is the widest clearance reach of the cached queries, in cells
+	reach       int
+	stats       CacheStats
+	unsubscribe func()
+}
+
+// NewPathCache creates a new instance of PathCache holding up to capacity
+// paths found on grid.
+func NewPathCache(grid NavGrid, capacity int) PathCache {
+	c := &pathCache{
+		grid:     grid,
+		capacity: max(capacity, 1),
+		entries:  make(map[cacheKey]*list.Element),
+		order:    list.New(),
+	}
+	c.unsubscribe = grid.Subscribe(c.Invalidate)
+	return c
+}
+
+// Getters and Setters for grid
+
+func (c *pathCache) GetGrid() NavGrid {
+	return c.grid
+}
+
+// FindPath answers from the cache, or searches grid and caches the path.
+func (c *pathCache) FindPath(start, target rl.Vector3, opts Options) Result {
+	return c.FindPathContext(context.Background(), start, target, opts)
+}
+
+// FindPathContext is FindPath with a context for the search on a miss.
+func (c *pathCache) FindPathContext(ctx context.Context, start, target rl.Vector3, opts Options) Result {
+	if opts.Cost != nil && opts.Profile == "" {
+		return FindPathContext(ctx, c.grid, start, target, opts)
+	}
+	c.grid.RLock()
+	key := cacheKey{
+		profile: opts.Profile,
+		start:   getNodeFromWorldPos(c.grid, start, opts).cell,
+		target:  getNodeFromWorldPos(c.grid, target, opts).cell,
+		shape:   newPathShape(opts),
+	}
+	c.grid.RUnlock()
+
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		c.stats.Hits++
+		result := element.Value.(*cacheEntry).result
+		c.mu.Unlock()
+		// Callers may trim the path they follow, hand out a copy
+		result.Path = append([]rl.Vector3(nil), result.Path...)
+		result.Expanded = 0
+		return result
+	}
+	c.stats.Misses++
+	generation := c.generation
+	c.mu.Unlock()
+
+	result := FindPathContext(ctx, c.grid, start, target, opts)
+	if result.Status != StatusFound {
+		return result
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return result
+	}
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+	}
+	cached := result
+	cached.Path = append([]rl.Vector3(nil), result.Path...)
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, result: cached})
+	c.reach = max(c.reach, clearanceReach(c.grid, opts))
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.stats.Evictions++
+	}
+	return result
+}
+
+// Invalidate drops every cached path passing within a cell of the cells
+// between min and max, or within the clearance of the agents it was found
+// for. The cache calls it itself when its grid changes.
+func (c *pathCache) Invalidate(min, max Cell) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Corner rules and line of sight look at the cells next to a path too
+	grow := 1 + c.reach
+	lo := c.grid.CellToWorld(Cell{min.X - grow, min.Y - 1, min.Z - grow})
+	hi := c.grid.CellToWorld(Cell{max.X + grow, max.Y + 1, max.Z + grow})
+	half := c.grid.GetCellSize() / 2
+	box := rl.NewBoundingBox(
+		rl.NewVector3(lo.X-half, lo.Y-half, lo.Z-half),
+		rl.NewVector3(hi.X+half, hi.Y+half, hi.Z+half),
+	)
+
+	c.generation++
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*cacheEntry)
+		if pathCrossesBox(entry.result.Path, box) {
+			c.order.Remove(element)
+			delete(c.entries, entry.key)
+			c.stats.Invalidations++
+		}
+		element = next
+	}
+}
+
+// Clear drops every cached path.
+func (c *pathCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.stats.Invalidations += uint64(c.order.Len())
+	c.order.Init()
+	clear(c.entries)
+}
+
+// Getters and Setters for stats
+
+func (c *pathCache) GetStats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Len returns the number of cached paths.
+func (c *pathCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Close stops following changes to the grid.
+func (c *pathCache) Close() {
+	if c.unsubscribe != nil {
+		c.unsubscribe()
+		c.unsubscribe = nil
+	}
+}
+
+// findPathCached answers a query on grid from cache, which must have been
+// created for that grid.
+func findPathCached(ctx context.Context, cache PathCache, grid NavGrid, start, target rl.Vector3, opts Options) Result {
+	if cache.GetGrid() != grid {
+		return Result{Status: StatusUnreachable, Err: ErrCacheGrid}
+	}
+	return cache.FindPathContext(ctx, start, target, opts)
+}
+
+// pathCrossesBox reports whether any segment of path passes through box.
+func pathCrossesBox(path []rl.Vector3, box rl.BoundingBox) bool {
+	for i := range path {
+		from := path[max(i-1, 0)]
+		if segmentHitsBox(from, path[i], box) {
+			return true
+		}
+	}
+	return false
+}
+
+// segmentHitsBox clips the segment from a to b against each slab of box.
+func segmentHitsBox(a, b rl.Vector3, box rl.BoundingBox) bool {
+	enter, exit := float32(0), float32(1)
+	axes := [3][4]float32{
+		{a.X, b.X, box.Min.X, box.Max.X},
+		{a.Y, b.Y, box.Min.Y, box.Max.Y},
+		{a.Z, b.Z, box.Min.Z, box.Max.Z},
+	}
+	for _, axis := range axes {
+		from, delta, lo, hi := axis[0], axis[1]-axis[0], axis[2], axis[3]
+		if delta == 0 {
+			if from < lo || from > hi {
+				return false
+			}
+			continue
+		}
+		t0, t1 := (lo-from)/delta, (hi-from)/delta
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		enter, exit = max(enter, t0), min(exit, t1)
+		if enter > exit {
+			return false
+		}
+	}
+	return true
+}
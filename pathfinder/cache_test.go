@@ -0,0 +1,134 @@
+package pathfinder
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestCacheHits(t *testing.T) {
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 32, Y: 1, Z: 32})
+	cache := NewPathCache(grid, 8)
+	defer cache.Close()
+	opts := DefaultOptions()
+	start, target := rl.NewVector3(1, 0, 1), rl.NewVector3(20, 0, 25)
+
+	first := cache.FindPath(start, target, opts)
+	second := cache.FindPath(start, target, opts)
+	if first.Status != StatusFound || second.Status != StatusFound || first.Cost != second.Cost {
+		t.Fatalf("got %s at %.4f then %s at %.4f", first.Status, first.Cost, second.Status, second.Cost)
+	}
+	if second.Expanded != 0 {
+		t.Errorf("hit expanded %d nodes", second.Expanded)
+	}
+	// Trimming a returned path must not reach the cached one
+	second.Path[0] = rl.NewVector3(-1, -1, -1)
+	if third := cache.FindPath(start, target, opts); third.Path[0] != first.Path[0] {
+		t.Errorf("cached path was changed through a result")
+	}
+	if stats := cache.GetStats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("got %+v, want 2 hits and 1 miss", stats)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 32, Y: 1, Z: 32})
+	cache := NewPathCache(grid, 2)
+	defer cache.Close()
+	opts := DefaultOptions()
+	start := rl.NewVector3(1, 0, 1)
+	a, b, c := rl.NewVector3(10, 0, 10), rl.NewVector3(20, 0, 10), rl.NewVector3(10, 0, 20)
+
+	cache.FindPath(start, a, opts)
+	cache.FindPath(start, b, opts)
+	cache.FindPath(start, a, opts) // a is now the most recently used
+	cache.FindPath(start, c, opts) // evicts b
+	if cache.Len() != 2 || cache.GetStats().Evictions != 1 {
+		t.Fatalf("got %d paths and %+v, want 2 paths and 1 eviction", cache.Len(), cache.GetStats())
+	}
+	before := cache.GetStats()
+	cache.FindPath(start, a, opts)
+	cache.FindPath(start, b, opts)
+	after := cache.GetStats()
+	if after.Hits != before.Hits+1 || after.Misses != before.Misses+1 {
+		t.Errorf("got %+v after %+v, want a hit on a and a miss on b", after, before)
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 32, Y: 1, Z: 32})
+	cache := NewPathCache(grid, 8)
+	defer cache.Close()
+	opts := DefaultOptions()
+	start, target := rl.NewVector3(1, 0, 5), rl.NewVector3(30, 0, 5)
+
+	cache.FindPath(start, target, opts)
+	grid.SetBlocked(Cell{X: 15, Z: 28}, true)
+	if cache.Len() != 1 {
+		t.Fatalf("a change far from the path dropped it")
+	}
+	grid.SetBlocked(Cell{X: 15, Z: 5}, true)
+	if cache.Len() != 0 || cache.GetStats().Invalidations != 1 {
+		t.Fatalf("got %d paths and %+v, want the path dropped", cache.Len(), cache.GetStats())
+	}
+	result := cache.FindPath(start, target, opts)
+	for _, point := range result.Path {
+		if grid.WorldToCell(point) == (Cell{X: 15, Z: 5}) {
+			t.Fatalf("path walks through the new wall")
+		}
+	}
+}
+
+func TestCacheOptionsApart(t *testing.T) {
+	// A wall with a one cell gap that only a point agent fits through
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 32, Y: 1, Z: 32})
+	for z := 0; z < 32; z++ {
+		if z != 10 {
+			grid.SetBlocked(Cell{X: 16, Z: z}, true)
+		}
+	}
+	cache := NewPathCache(grid, 8)
+	defer cache.Close()
+	start, target := rl.NewVector3(2, 0, 10), rl.NewVector3(30, 0, 10)
+
+	point := DefaultOptions()
+	wide := DefaultOptions()
+	wide.AgentRadius = 1
+	wide.AllowPartial = false
+	if result := cache.FindPath(start, target, point); result.Status != StatusFound {
+		t.Fatalf("point agent: got %s", result.Status)
+	}
+	if result := cache.FindPath(start, target, wide); result.Status != StatusUnreachable {
+		t.Errorf("wide agent: got %s from the point agent's path", result.Status)
+	}
+
+	costly := DefaultOptions()
+	costly.CostMap = NewCostMap(32, 32, 1)
+	for z := 0; z < 32; z++ {
+		costly.CostMap.SetCost(16, z, 50)
+	}
+	plain := cache.FindPath(start, target, point)
+	weighted := cache.FindPath(start, target, costly)
+	if weighted.Cost <= plain.Cost {
+		t.Errorf("cost map: got %.4f, want more than %.4f", weighted.Cost, plain.Cost)
+	}
+}
+
+func TestCacheCostFunc(t *testing.T) {
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 32, Y: 1, Z: 32})
+	cache := NewPathCache(grid, 8)
+	defer cache.Close()
+	start, target := rl.NewVector3(1, 0, 1), rl.NewVector3(20, 0, 20)
+
+	opts := DefaultOptions()
+	opts.Cost = func(from, to Cell, distance float64) float64 { return 2 * distance }
+	cache.FindPath(start, target, opts)
+	if cache.Len() != 0 {
+		t.Errorf("cached a query with a cost function and no profile")
+	}
+	opts.Profile = "double"
+	cache.FindPath(start, target, opts)
+	if cache.Len() != 1 {
+		t.Errorf("did not cache a query with a cost function under a profile")
+	}
+}
@@ -0,0 +1,369 @@
+package pathfinder
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// CoopAgent is one unit of a group planned by a Cooperative.
+type CoopAgent struct {
+	Start  rl.Vector3
+	Target rl.Vector3
+	// Priority orders the planning, higher priorities plan first and the
+	// others route around them; agents of equal priority keep their order
+	Priority int
+}
+
+// CoopPlan is the plan of one CoopAgent. Path holds the waypoints of the
+// window with the waits dropped, followed by the way to the target.
+type CoopPlan struct {
+	Result
+	// Steps holds the position at each time step of the window, Steps[0] is the start
+	Steps []rl.Vector3
+}
+
+// Cooperative plans the paths of a group of ground agents with Windowed
+// Hierarchical Cooperative A*. Agents are planned one after the other in
+// space and time: each one books the cells it occupies at every time step
+// of the window in a reservation table, and the agents planned after it
+// wait or walk around those cells. Beyond the window each agent simply
+// follows the true distance to its target, which also guides the search.
+//
+// A time step is one move to a neighbouring cell or one wait. Agents should
+// advance through Steps together and call Plan again, with their current
+// positions, before they walk through half of the window. Rotating the
+// priorities between plans keeps agents from blocking each other for good.
+// Like Hierarchy, a Cooperative belongs to the game loop.
+type Cooperative interface {
+	GetWindow() int
+	SetWindow(newWindow int)
+	Plan(agents []CoopAgent) []CoopPlan
+	IsReserved(cell Cell, step int) bool
+	Close()
+}
+
+// reservation is a column at a time step.
+type reservation struct {
+	column int
+	step   int
+}
+
+type cooperative struct {
+	grid    NavGrid
+	options Options
+	window  int
+	columns int
+	rows    int
+
+	// reservations holds the planning rank, from 1, of the agent booking
+	// each column at each time step
+	reservations map[reservation]int
+	// fields hold the true distance to each target column
+	fields map[int]*flowField
+
+	nodes     map[reservation]*coopNode
+	open      coopQueue
+	neighbors []Node
+}
+
+// NewCooperative creates a new instance of Cooperative over the ground of
+// grid, planning window time steps ahead with reservations.
+func NewCooperative(grid NavGrid, window int, opts Options) Cooperative {
+	opts.Mode = ModeGround
+	size := grid.GetSize()
+	return &cooperative{
+		grid:         grid,
+		options:      opts,
+		window:       max(window, 1),
+		columns:      size.X,
+		rows:         size.Z,
+		reservations: make(map[reservation]int),
+		fields:       make(map[int]*flowField),
+		nodes:        make(map[reservation]*coopNode),
+	}
+}
+
+// Getters and Setters for window
+func (c *cooperative) GetWindow() int {
+	return c.window
+}
+
+func (c *cooperative) SetWindow(newWindow int) {
+	c.window = max(newWindow, 1)
+}
+
+// IsReserved reports whether an agent booked the column of cell at step
+// in the last Plan.
+func (c *cooperative) IsReserved(cell Cell, step int) bool {
+	column, ok := c.index(cell.X, cell.Z)
+	return ok && c.reservations[reservation{column, step}] != 0
+}
+
+// Close stops following changes to the grid.
+func (c *cooperative) Close() {
+	for column, field := range c.fields {
+		field.Close()
+		delete(c.fields, column)
+	}
+}
+
+// Plan clears the reservation table and plans every agent in order of
+// priority. The plans are returned in the order of agents.
+func (c *cooperative) Plan(agents []CoopAgent) []CoopPlan {
+	// Bring the distance fields up to date before locking the grid, they
+	// lock it themselves
+	used := make(map[int]bool)
+	for _, agent := range agents {
+		cell := c.grid.WorldToCell(agent.Target)
+		column, ok := c.index(cell.X, cell.Z)
+		if !ok {
+			continue
+		}
+		used[column] = true
+		field := c.fields[column]
+		if field == nil {
+			field = NewFlowField(c.grid, c.options).(*flowField)
+			c.fields[column] = field
+		}
+		field.SetGoal(agent.Target)
+		field.Update(0)
+	}
+	for column, field := range c.fields {
+		if !used[column] {
+			field.Close()
+			delete(c.fields, column)
+		}
+	}
+
+	order := make([]int, len(agents))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return agents[order[i]].Priority > agents[order[j]].Priority
+	})
+
+	c.grid.RLock()
+	defer c.grid.RUnlock()
+	clear(c.reservations)
+	plans := make([]CoopPlan, len(agents))
+	for rank, i := range order {
+		plans[i] = c.planAgent(agents[i], rank+1)
+	}
+	return plans
+}
+
+// planAgent plans one agent around the reservations of the agents planned
+// before it and books its own cells under rank.
+func (c *cooperative) planAgent(agent CoopAgent, rank int) CoopPlan {
+	startNode := getNodeFromWorldPos(c.grid, agent.Start, c.options)
+	targetNode := getNodeFromWorldPos(c.grid, agent.Target, c.options)
+	start, ok := c.index(startNode.cell.X, startNode.cell.Z)
+	if !ok {
+		return CoopPlan{Result: Result{Status: StatusUnreachable, Err: ErrOutOfBounds}}
+	}
+	target, ok := c.index(targetNode.cell.X, targetNode.cell.Z)
+	if !ok {
+		return c.hold(start, rank, Result{Status: StatusUnreachable, Err: ErrOutOfBounds})
+	}
+	field := c.fields[target].ready
+	if math.IsInf(field.cost[start], 1) {
+		err := ErrUnreachable
+		if !canStand(c.grid, targetNode.cell, c.options) {
+			err = ErrBlocked
+		}
+		return c.hold(start, rank, Result{Status: StatusUnreachable, Err: err})
+	}
+
+	end, expanded, err := c.search(start, target, field.cost)
+	if err != nil && !c.options.AllowPartial {
+		return c.hold(start, rank, Result{Status: StatusUnreachable, Expanded: expanded, Err: err})
+	}
+
+	// A plan cut short stands still after its last step
+	columns := make([]int, c.window+1)
+	for step := end.key.step; step <= c.window; step++ {
+		columns[step] = end.key.column
+	}
+	for node := end; node != nil; node = node.parent {
+		columns[node.key.step] = node.key.column
+	}
+	plan := CoopPlan{Steps: make([]rl.Vector3, len(columns))}
+	for step, column := range columns {
+		c.reserve(column, step, rank)
+		plan.Steps[step] = c.position(column)
+		if step == 0 || column != columns[step-1] {
+			plan.Path = append(plan.Path, plan.Steps[step])
+		}
+	}
+	plan.Expanded = expanded
+
+	if err != nil {
+		plan.Status, plan.Cost, plan.Err = StatusPartial, end.g, err
+		return plan
+	}
+	// Beyond the window the agent follows the field, ignoring the others
+	for next := field.next[end.key.column]; next >= 0; next = field.next[next] {
+		plan.Path = append(plan.Path, c.position(next))
+	}
+	plan.Status, plan.Cost = StatusFound, end.g+field.cost[end.key.column]
+	return plan
+}
+
+// search runs A* through space and time from start until the window is
+// filled, guided by the true distance cost to target. When the window
+// cannot be filled it returns the deepest node reached with the reason.
+func (c *cooperative) search(start, target int, cost []float64) (*coopNode, int, error) {
+	clear(c.nodes)
+	c.open = c.open[:0]
+	first := c.relax(reservation{start, 0}, nil, 0, cost)
+	deepest := first
+	expanded := 0
+	wait := float64(c.grid.GetCellSize())
+
+	for c.open.Len() > 0 {
+		current := heap.Pop(&c.open).(*coopNode)
+		current.closed = true
+		expanded++
+
+		if current.key.step == c.window {
+			return current, expanded, nil
+		}
+		if current.key.step > deepest.key.step || (current.key.step == deepest.key.step && current.f() < deepest.f()) {
+			deepest = current
+		}
+		if c.options.MaxNodes > 0 && expanded >= c.options.MaxNodes {
+			return deepest, expanded, ErrNodeLimit
+		}
+
+		step := current.key.step + 1
+		from := current.key.column
+		// Waiting is free only once the agent stands on its target
+		if c.free(from, from, step) {
+			waitCost := wait
+			if from == target {
+				waitCost = 0
+			}
+			c.relax(reservation{from, step}, current, current.g+waitCost, cost)
+		}
+
+		node := getGroundNode(c.grid, from%c.columns, from/c.columns)
+		c.neighbors = getGroundNeighbors(c.grid, &node, c.options, c.neighbors[:0])
+		for i := range c.neighbors {
+			neighbor := &c.neighbors[i]
+			to, _ := c.index(neighbor.cell.X, neighbor.cell.Z)
+			if math.IsInf(cost[to], 1) || !c.free(from, to, step) {
+				continue
+			}
+			stepped, ok := stepCost(&node, neighbor, c.options)
+			if !ok {
+				continue
+			}
+			c.relax(reservation{to, step}, current, current.g+stepped, cost)
+		}
+	}
+	return deepest, expanded, ErrReserved
+}
+
+// relax records g for the node at key, reached from parent, when it
+// improves on the cost already known, and returns the node.
+func (c *cooperative) relax(key reservation, parent *coopNode, g float64, cost []float64) *coopNode {
+	node := c.nodes[key]
+	if node == nil {
+		node = &coopNode{key: key, g: g, h: cost[key.column], parent: parent}
+		c.nodes[key] = node
+		heap.Push(&c.open, node)
+		return node
+	}
+	if !node.closed && g < node.g {
+		node.g, node.parent = g, parent
+		heap.Fix(&c.open, node.index)
+	}
+	return node
+}
+
+// free reports whether moving from one column to another, arriving at
+// step, avoids every agent planned so far.
+func (c *cooperative) free(from, to, step int) bool {
+	if c.reservations[reservation{to, step}] != 0 {
+		return false
+	}
+	// Two agents cannot swap cells by walking through each other
+	holder := c.reservations[reservation{to, step - 1}]
+	return from == to || holder == 0 || c.reservations[reservation{from, step}] != holder
+}
+
+// reserve books column at step for rank unless an earlier agent holds it,
+// which only happens to agents sharing a start or stuck in place.
+func (c *cooperative) reserve(column, step, rank int) {
+	key := reservation{column, step}
+	if c.reservations[key] == 0 {
+		c.reservations[key] = rank
+	}
+}
+
+// hold keeps the agent on its start for the whole window, so the agents
+// planned after it walk around it.
+func (c *cooperative) hold(start, rank int, result Result) CoopPlan {
+	plan := CoopPlan{Result: result, Steps: make([]rl.Vector3, c.window+1)}
+	for step := range plan.Steps {
+		c.reserve(start, step, rank)
+		plan.Steps[step] = c.position(start)
+	}
+	return plan
+}
+
+// position returns the point on the terrain at the centre of column.
+func (c *cooperative) position(column int) rl.Vector3 {
+	return getGroundNode(c.grid, column%c.columns, column/c.columns).position
+}
+
+func (c *cooperative) index(x, z int) (int, bool) {
+	if x < 0 || z < 0 || x >= c.columns || z >= c.rows {
+		return 0, false
+	}
+	return z*c.columns + x, true
+}
+
+// coopNode is a column at a time step in the space-time search.
+type coopNode struct {
+	key    reservation
+	g, h   float64
+	parent *coopNode
+	index  int // Index in the open set
+	closed bool
+}
+
+func (n *coopNode) f() float64 {
+	return n.g + n.h
+}
+
+type coopQueue []*coopNode
+
+func (q coopQueue) Len() int { return len(q) }
+func (q coopQueue) Less(i, j int) bool {
+	if fi, fj := q[i].f(), q[j].f(); fi != fj {
+		return fi < fj
+	}
+	// Prefer the node further along in time to fill the window sooner
+	return q[i].key.step > q[j].key.step
+}
+func (q coopQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+func (q *coopQueue) Push(x any) {
+	node := x.(*coopNode)
+	node.index = len(*q)
+	*q = append(*q, node)
+}
+func (q *coopQueue) Pop() any {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return node
+}
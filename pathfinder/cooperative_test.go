@@ -0,0 +1,110 @@
+package pathfinder
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// newCorridorGrid returns a corridor one cell wide along z = 1 with a single
+// bay at x = 6 where an agent can step aside.
+func newCorridorGrid() NavGrid {
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 9, Y: 1, Z: 3})
+	for x := 0; x < 9; x++ {
+		grid.SetBlocked(Cell{X: x, Z: 0}, x != 6)
+		grid.SetBlocked(Cell{X: x, Z: 2}, true)
+	}
+	return grid
+}
+
+// checkConflicts fails when two plans hold the same cell at a time step or
+// swap cells between two steps, both in their steps and in the reservation
+// table of c.
+func checkConflicts(t *testing.T, grid NavGrid, c Cooperative, plans []CoopPlan) {
+	t.Helper()
+	cells := make([][]Cell, len(plans))
+	for i, plan := range plans {
+		for _, pos := range plan.Steps {
+			cells[i] = append(cells[i], grid.WorldToCell(pos))
+		}
+	}
+	for i := range cells {
+		for j := i + 1; j < len(cells); j++ {
+			for step := range cells[i] {
+				if cells[i][step] == cells[j][step] {
+					t.Errorf("agents %d and %d both stand on %v at step %d", i, j, cells[i][step], step)
+				}
+				if step > 0 && cells[i][step] == cells[j][step-1] && cells[j][step] == cells[i][step-1] {
+					t.Errorf("agents %d and %d swap %v and %v at step %d", i, j, cells[i][step], cells[j][step], step)
+				}
+			}
+		}
+	}
+
+	// Every cell of a plan is booked, by that plan alone
+	table := c.(*cooperative).reservations
+	owner := make(map[reservation]int)
+	for i, plan := range cells {
+		for step, cell := range plan {
+			if !c.IsReserved(cell, step) {
+				t.Errorf("agent %d holds %v at step %d without a reservation", i, cell, step)
+			}
+			key := reservation{cell.Z*grid.GetSize().X + cell.X, step}
+			if previous, ok := owner[key]; ok && previous != i {
+				t.Errorf("agents %d and %d share the reservation of %v at step %d", previous, i, cell, step)
+			}
+			owner[key] = i
+		}
+	}
+	for key, rank := range table {
+		for other, otherRank := range table {
+			if other.step != key.step || rank == otherRank {
+				continue
+			}
+			if table[reservation{key.column, key.step + 1}] == otherRank && table[reservation{other.column, key.step + 1}] == rank {
+				t.Errorf("reservations of ranks %d and %d swap columns %d and %d at step %d", rank, otherRank, key.column, other.column, key.step+1)
+			}
+		}
+	}
+}
+
+func TestCooperativeCorridorSwap(t *testing.T) {
+	grid := newCorridorGrid()
+	c := NewCooperative(grid, 24, DefaultOptions())
+	defer c.Close()
+	left, right := rl.NewVector3(0, 0, 1), rl.NewVector3(8, 0, 1)
+	plans := c.Plan([]CoopAgent{
+		{Start: left, Target: right},
+		{Start: right, Target: left},
+	})
+
+	for i, target := range []rl.Vector3{right, left} {
+		if plans[i].Status != StatusFound {
+			t.Fatalf("agent %d: %s, want found", i, plans[i].Status)
+		}
+		if last := plans[i].Steps[len(plans[i].Steps)-1]; grid.WorldToCell(last) != grid.WorldToCell(target) {
+			t.Errorf("agent %d ends the window at %v, want %v", i, last, target)
+		}
+	}
+	checkConflicts(t, grid, c, plans)
+}
+
+func TestCooperativeCrossing(t *testing.T) {
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 9, Y: 1, Z: 9})
+	c := NewCooperative(grid, 16, DefaultOptions())
+	defer c.Close()
+	// Four agents crossing through the centre from each side
+	agents := []CoopAgent{
+		{Start: rl.NewVector3(0, 0, 4), Target: rl.NewVector3(8, 0, 4)},
+		{Start: rl.NewVector3(8, 0, 4), Target: rl.NewVector3(0, 0, 4)},
+		{Start: rl.NewVector3(4, 0, 0), Target: rl.NewVector3(4, 0, 8), Priority: 1},
+		{Start: rl.NewVector3(4, 0, 8), Target: rl.NewVector3(4, 0, 0)},
+	}
+	plans := c.Plan(agents)
+	for i, plan := range plans {
+		if plan.Status != StatusFound {
+			t.Fatalf("agent %d: %s, want found", i, plan.Status)
+		}
+	}
+	checkConflicts(t, grid, c, plans)
+}
@@ -0,0 +1,113 @@
+package pathfinder
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+)
+
+// Impassable marks a cost map cell no path may enter. Any negative cost
+// does the same.
+const Impassable float32 = -1
+
+// CostMap multiplies the cost of walking over each grid column. Tiles are
+// scale by scale cells large and cells outside the map cost 1.
+//
+// Costs below 1 let paths cost less than the straight line distance the
+// heuristics assume, so give the cheapest terrain (roads) a cost of 1 and
+// raise everything else. The map is not safe to change while searches run,
+// and hierarchies built over it must be rebuilt after SetCost.
+type CostMap interface {
+	GetSize() (columns, rows int)
+	GetScale() int
+	GetCost(x, z int) float32
+	SetCost(x, z int, cost float32)
+}
+
+type costMap struct {
+	columns int
+	rows    int
+	scale   int
+	costs   []float32
+}
+
+// NewCostMap creates a new instance of CostMap with columns by rows tiles
+// costing 1, each covering scale by scale grid cells.
+func NewCostMap(columns, rows, scale int) CostMap {
+	costs := make([]float32, columns*rows)
+	for i := range costs {
+		costs[i] = 1
+	}
+	return &costMap{
+		columns: columns,
+		rows:    rows,
+		scale:   max(scale, 1),
+		costs:   costs,
+	}
+}
+
+// NewCostMapFromImage creates a new instance of CostMap with one tile per
+// pixel of img. White pixels cost 1, darker ones up to maxCost and black
+// pixels are impassable.
+func NewCostMapFromImage(img image.Image, maxCost float32, scale int) CostMap {
+	bounds := img.Bounds()
+	m := NewCostMap(bounds.Dx(), bounds.Dy(), scale)
+	for z := 0; z < bounds.Dy(); z++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			gray := color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+z)).(color.Gray).Y
+			if gray == 0 {
+				m.SetCost(x, z, Impassable)
+				continue
+			}
+			m.SetCost(x, z, 1+(maxCost-1)*float32(255-gray)/254)
+		}
+	}
+	return m
+}
+
+// LoadCostMapImage reads a PNG or JPEG grayscale cost map, see NewCostMapFromImage.
+func LoadCostMapImage(path string, maxCost float32, scale int) (CostMap, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("pathfinder: decode cost map %s: %w", path, err)
+	}
+	return NewCostMapFromImage(img, maxCost, scale), nil
+}
+
+// Getters and Setters for costMap
+func (m *costMap) GetSize() (int, int) {
+	return m.columns, m.rows
+}
+
+func (m *costMap) GetScale() int {
+	return m.scale
+}
+
+// GetCost returns the multiplier of the tile holding grid column x, z.
+func (m *costMap) GetCost(x, z int) float32 {
+	if x < 0 || z < 0 {
+		return 1
+	}
+	x, z = x/m.scale, z/m.scale
+	if x >= m.columns || z >= m.rows {
+		return 1
+	}
+	return m.costs[z*m.columns+x]
+}
+
+// SetCost sets the multiplier of tile x, z.
+func (m *costMap) SetCost(x, z int, cost float32) {
+	if x < 0 || z < 0 || x >= m.columns || z >= m.rows {
+		return
+	}
+	m.costs[z*m.columns+x] = cost
+}
@@ -0,0 +1,349 @@
+package pathfinder
+
+import (
+	"container/heap"
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Planner keeps a path to one target valid while the grid changes. It runs
+// D* Lite on the ground of a grid: the search grows from the target, so
+// when cells change only the costs they affect are repaired instead of
+// searching again from scratch.
+//
+// Grid updates are picked up through Subscribe; call NotifyChanged for
+// changes the grid does not report, like an edited cost map. Like
+// Hierarchy, a Planner belongs to the game loop, but the grid may be
+// updated from any goroutine: the changes it reports are only read and
+// written under the grid lock.
+type Planner interface {
+	Plan(start, target rl.Vector3) Result
+	Move(pos rl.Vector3)
+	NotifyChanged(min, max Cell)
+	IsDirty() bool
+	Replan() Result
+	Close()
+}
+
+// plannerKey orders cells in the D* Lite open set.
+type plannerKey [2]float64
+
+func (k plannerKey) less(other plannerKey) bool {
+	return k[0] < other[0] || (k[0] == other[0] && k[1] < other[1])
+}
+
+type planner struct {
+	grid    NavGrid
+	options Options
+	columns int
+	rows    int
+
+	start  Cell
+	last   Cell
+	target Cell
+	km     float64
+	// g is the settled cost to the target of each column, rhs the cost
+	// seen from its neighbours; they differ while the column is queued
+	g   []float64
+	rhs []float64
+	// keys and slots locate the queued columns in open, slot -1 when not queued
+	keys  []plannerKey
+	slots []int
+	open  plannerQueue
+
+	planned bool
+	// changes are appended by grid updates under its write lock, everything
+	// else touches them under its read lock
+	changes     [][2]Cell
+	neighbors   []Node
+	around      []int
+	unsubscribe func()
+}
+
+// NewPlanner creates a new instance of Planner over the ground of grid.
+func NewPlanner(grid NavGrid, opts Options) Planner {
+	opts.Mode = ModeGround
+	size := grid.GetSize()
+	count := size.X * size.Z
+	p := &planner{
+		grid:    grid,
+		options: opts,
+		columns: size.X,
+		rows:    size.Z,
+		g:       make([]float64, count),
+		rhs:     make([]float64, count),
+		keys:    make([]plannerKey, count),
+		slots:   make([]int, count),
+	}
+	p.open.planner = p
+	p.unsubscribe = grid.Subscribe(p.noteChange)
+	return p
+}
+
+// Plan forgets the previous search and finds a path from start to target.
+func (p *planner) Plan(start, target rl.Vector3) Result {
+	p.grid.RLock()
+	defer p.grid.RUnlock()
+
+	startNode := getNodeFromWorldPos(p.grid, start, p.options)
+	targetNode := getNodeFromWorldPos(p.grid, target, p.options)
+	p.planned = false
+	p.changes = p.changes[:0]
+	if !p.grid.InBounds(startNode.cell) || !p.grid.InBounds(targetNode.cell) {
+		return Result{Status: StatusUnreachable, Err: ErrOutOfBounds}
+	}
+	if !canStand(p.grid, targetNode.cell, p.options) {
+		return Result{Status: StatusUnreachable, Err: ErrBlocked}
+	}
+
+	for i := range p.g {
+		p.g[i] = math.Inf(1)
+		p.rhs[i] = math.Inf(1)
+		p.slots[i] = -1
+	}
+	p.open.items = p.open.items[:0]
+	p.start, p.last, p.target = startNode.cell, startNode.cell, targetNode.cell
+	p.km = 0
+	p.planned = true
+
+	goal := p.index(p.target)
+	p.rhs[goal] = 0
+	p.queue(goal)
+	return p.search()
+}
+
+// Move tells the planner the agent now stands at pos, so the next Replan
+// starts from there.
+func (p *planner) Move(pos rl.Vector3) {
+	if !p.planned {
+		return
+	}
+	p.grid.RLock()
+	defer p.grid.RUnlock()
+	cell := getNodeFromWorldPos(p.grid, pos, p.options).cell
+	if !p.grid.InBounds(cell) || cell == p.start {
+		return
+	}
+	p.start = cell
+}
+
+// NotifyChanged records that the cells between min and max changed. It only
+// takes note, the costs are repaired by the next Replan.
+func (p *planner) NotifyChanged(min, max Cell) {
+	p.grid.RLock()
+	defer p.grid.RUnlock()
+	p.noteChange(min, max)
+}
+
+// noteChange records a change while the grid is locked. The grid calls it
+// with the write lock held, so it must not lock the grid itself.
+func (p *planner) noteChange(min, max Cell) {
+	p.changes = append(p.changes, [2]Cell{min, max})
+}
+
+// IsDirty reports whether cells changed since the path was last planned.
+func (p *planner) IsDirty() bool {
+	p.grid.RLock()
+	defer p.grid.RUnlock()
+	return len(p.changes) > 0
+}
+
+// Replan repairs the search after the agent moved or cells changed and
+// returns the new path from the agent's position.
+func (p *planner) Replan() Result {
+	if !p.planned {
+		return Result{Status: StatusUnreachable, Err: ErrUnreachable}
+	}
+	p.grid.RLock()
+	defer p.grid.RUnlock()
+
+	// Moving the start shifts every queued key by the same amount, keep
+	// them comparable instead of recomputing the queue
+	if p.start != p.last {
+		p.km += p.heuristic(p.last, p.start)
+		p.last = p.start
+	}
+	// A cell decides the steps of its neighbours too: which of them can be
+	// entered and which diagonals squeeze past it. It also changes where
+	// large agents fit further away.
+	reach := 1 + clearanceReach(p.grid, p.options)
+	for _, change := range p.changes {
+		for z := max(change[0].Z-reach, 0); z <= min(change[1].Z+reach, p.rows-1); z++ {
+			for x := max(change[0].X-reach, 0); x <= min(change[1].X+reach, p.columns-1); x++ {
+				p.updateVertex(z*p.columns + x)
+			}
+		}
+	}
+	p.changes = p.changes[:0]
+	return p.search()
+}
+
+// Close stops following changes to the grid.
+func (p *planner) Close() {
+	if p.unsubscribe != nil {
+		p.unsubscribe()
+		p.unsubscribe = nil
+	}
+}
+
+// search settles costs until the start is consistent and walks the path.
+func (p *planner) search() Result {
+	start := p.index(p.start)
+	expanded := 0
+	for len(p.open.items) > 0 && (p.keys[p.open.items[0]].less(p.key(start)) || p.rhs[start] != p.g[start]) {
+		if p.options.MaxNodes > 0 && expanded >= p.options.MaxNodes {
+			// The queue is kept, the next Replan carries on from here
+			return Result{Status: StatusUnreachable, Expanded: expanded, Err: ErrNodeLimit}
+		}
+		expanded++
+
+		u := p.open.items[0]
+		if old, fresh := p.keys[u], p.key(u); old.less(fresh) {
+			p.keys[u] = fresh
+			heap.Fix(&p.open, p.slots[u])
+			continue
+		}
+		heap.Remove(&p.open, p.slots[u])
+		if p.g[u] > p.rhs[u] {
+			p.g[u] = p.rhs[u]
+		} else {
+			p.g[u] = math.Inf(1)
+			p.updateVertex(u)
+		}
+		p.around = p.predecessors(u, p.around[:0])
+		for _, s := range p.around {
+			p.updateVertex(s)
+		}
+	}
+
+	if math.IsInf(p.g[start], 1) {
+		return Result{Status: StatusUnreachable, Expanded: expanded, Err: ErrUnreachable}
+	}
+	path, cost := p.walk()
+	if path == nil {
+		return Result{Status: StatusUnreachable, Expanded: expanded, Err: ErrUnreachable}
+	}
+	return Result{Status: StatusFound, Path: postProcess(p.grid, path, p.options), Cost: cost, Expanded: expanded}
+}
+
+// walk follows the cheapest neighbour from the start down to the target.
+func (p *planner) walk() ([]rl.Vector3, float64) {
+	current := getGroundNode(p.grid, p.start.X, p.start.Z)
+	path := []rl.Vector3{current.position}
+	cost := 0.0
+	for steps := 0; current.cell.X != p.target.X || current.cell.Z != p.target.Z; steps++ {
+		if steps > len(p.g) {
+			return nil, 0
+		}
+		best, bestCost, bestStep := Node{}, math.Inf(1), 0.0
+		p.neighbors = getGroundNeighbors(p.grid, &current, p.options, p.neighbors[:0])
+		for _, neighbor := range p.neighbors {
+			step, ok := stepCost(&current, &neighbor, p.options)
+			if !ok {
+				continue
+			}
+			if total := step + p.g[p.index(neighbor.cell)]; total < bestCost {
+				best, bestCost, bestStep = neighbor, total, step
+			}
+		}
+		if math.IsInf(bestCost, 1) {
+			return nil, 0
+		}
+		current = best
+		cost += bestStep
+		path = append(path, current.position)
+	}
+	return path, cost
+}
+
+// updateVertex recomputes the cost of column u from its neighbours and
+// queues it when that disagrees with its settled cost.
+func (p *planner) updateVertex(u int) {
+	if u != p.index(p.target) {
+		p.rhs[u] = math.Inf(1)
+		node := getGroundNode(p.grid, u%p.columns, u/p.columns)
+		p.neighbors = getGroundNeighbors(p.grid, &node, p.options, p.neighbors[:0])
+		for _, neighbor := range p.neighbors {
+			if step, ok := stepCost(&node, &neighbor, p.options); ok {
+				p.rhs[u] = math.Min(p.rhs[u], step+p.g[p.index(neighbor.cell)])
+			}
+		}
+	}
+	if p.slots[u] >= 0 {
+		heap.Remove(&p.open, p.slots[u])
+	}
+	if p.g[u] != p.rhs[u] {
+		p.queue(u)
+	}
+}
+
+// predecessors appends the columns that may step onto column u. Blocked
+// columns are included, an agent standing on one can still walk off it.
+func (p *planner) predecessors(u int, predecessors []int) []int {
+	x, z := u%p.columns, u/p.columns
+	for dz := -1; dz <= 1; dz++ {
+		for dx := -1; dx <= 1; dx++ {
+			if (dx == 0 && dz == 0) || (dx != 0 && dz != 0 && p.options.Connectivity == Connect4) {
+				continue
+			}
+			if x+dx >= 0 && x+dx < p.columns && z+dz >= 0 && z+dz < p.rows {
+				predecessors = append(predecessors, (z+dz)*p.columns+x+dx)
+			}
+		}
+	}
+	return predecessors
+}
+
+func (p *planner) queue(u int) {
+	p.keys[u] = p.key(u)
+	heap.Push(&p.open, u)
+}
+
+func (p *planner) key(u int) plannerKey {
+	cost := math.Min(p.g[u], p.rhs[u])
+	return plannerKey{cost + p.heuristic(p.start, Cell{X: u % p.columns, Z: u / p.columns}) + p.km, cost}
+}
+
+// plannerSlack shrinks the heuristic a little: step costs are measured in
+// float32, and an estimate rounding above them breaks D* Lite's bounds.
+const plannerSlack = 1 - 1e-6
+
+// heuristic estimates the cost between two columns. The weight is left out,
+// D* Lite needs a consistent estimate to repair paths correctly.
+func (p *planner) heuristic(a, b Cell) float64 {
+	opts := p.options
+	opts.Weight = 1
+	return plannerSlack * estimate(getGroundNode(p.grid, a.X, a.Z).position, getGroundNode(p.grid, b.X, b.Z).position, opts)
+}
+
+func (p *planner) index(cell Cell) int {
+	return cell.Z*p.columns + cell.X
+}
+
+// plannerQueue is a heap of columns ordered by their keys.
+type plannerQueue struct {
+	planner *planner
+	items   []int
+}
+
+func (q *plannerQueue) Len() int { return len(q.items) }
+func (q *plannerQueue) Less(i, j int) bool {
+	return q.planner.keys[q.items[i]].less(q.planner.keys[q.items[j]])
+}
+func (q *plannerQueue) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.planner.slots[q.items[i]] = i
+	q.planner.slots[q.items[j]] = j
+}
+func (q *plannerQueue) Push(x any) {
+	u := x.(int)
+	q.planner.slots[u] = len(q.items)
+	q.items = append(q.items, u)
+}
+func (q *plannerQueue) Pop() any {
+	u := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	q.planner.slots[u] = -1
+	return u
+}
@@ -0,0 +1,106 @@
+package pathfinder
+
+import (
+	"math"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func plannerOptions() Options {
+	opts := DefaultOptions()
+	opts.Mode = ModeGround
+	opts.StringPull = false
+	opts.MaxNodes = 0
+	opts.AllowPartial = false
+	return opts
+}
+
+// checkFresh fails unless result costs the same as a fresh A* search from
+// start on grid.
+func checkFresh(t *testing.T, grid NavGrid, result Result, start, target rl.Vector3) {
+	t.Helper()
+	want := FindPath(grid, start, target, plannerOptions())
+	if result.Status != want.Status {
+		t.Fatalf("planner status %s, A* status %s", result.Status, want.Status)
+	}
+	if math.Abs(result.Cost-want.Cost) > 1e-9 {
+		t.Errorf("planner cost %.6f, A* cost %.6f", result.Cost, want.Cost)
+	}
+	if len(result.Path) > 0 && grid.WorldToCell(result.Path[0]) != grid.WorldToCell(start) {
+		t.Errorf("path starts at %v, want the cell of %v", result.Path[0], start)
+	}
+}
+
+func TestPlannerReplan(t *testing.T) {
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 24, Y: 1, Z: 24})
+	p := NewPlanner(grid, plannerOptions())
+	defer p.Close()
+	start, target := rl.NewVector3(2, 0, 12), rl.NewVector3(21, 0, 12)
+	checkFresh(t, grid, p.Plan(start, target), start, target)
+
+	// Walls added through the grid are reported by its subscription
+	for z := 4; z < 20; z++ {
+		grid.SetBlocked(Cell{X: 12, Z: z}, true)
+	}
+	if !p.IsDirty() {
+		t.Fatal("planner did not notice the grid change")
+	}
+	checkFresh(t, grid, p.Replan(), start, target)
+	if p.IsDirty() {
+		t.Error("planner still dirty after Replan")
+	}
+
+	// Opening a gap again, then closing the last way around
+	grid.SetBlocked(Cell{X: 12, Z: 12}, false)
+	checkFresh(t, grid, p.Replan(), start, target)
+	for z := 0; z < 24; z++ {
+		grid.SetBlocked(Cell{X: 12, Z: z}, true)
+	}
+	checkFresh(t, grid, p.Replan(), start, target)
+}
+
+func TestPlannerNotifyChanged(t *testing.T) {
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 16, Y: 1, Z: 16})
+	costs := NewCostMap(16, 16, 1)
+	opts := plannerOptions()
+	opts.CostMap = costs
+	p := NewPlanner(grid, opts)
+	defer p.Close()
+	start, target := rl.NewVector3(1, 0, 8), rl.NewVector3(14, 0, 8)
+	before := p.Plan(start, target)
+
+	// The grid does not report cost map edits, the caller does
+	for z := 0; z < 14; z++ {
+		costs.SetCost(8, z, 10)
+	}
+	p.NotifyChanged(Cell{X: 8}, Cell{X: 8, Z: 13})
+	result := p.Replan()
+	want := FindPath(grid, start, target, opts)
+	if math.Abs(result.Cost-want.Cost) > 1e-9 {
+		t.Errorf("planner cost %.6f after the cost change, A* cost %.6f", result.Cost, want.Cost)
+	}
+	if result.Cost <= before.Cost {
+		t.Errorf("cost %.6f did not grow from %.6f", result.Cost, before.Cost)
+	}
+}
+
+func TestPlannerMove(t *testing.T) {
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 24, Y: 1, Z: 24})
+	p := NewPlanner(grid, plannerOptions())
+	defer p.Close()
+	start, target := rl.NewVector3(2, 0, 2), rl.NewVector3(21, 0, 21)
+	p.Plan(start, target)
+
+	moved := rl.NewVector3(10, 0, 3)
+	p.Move(moved)
+	checkFresh(t, grid, p.Replan(), moved, target)
+
+	// Moving on and changing the grid are repaired together
+	moved = rl.NewVector3(15, 0, 6)
+	p.Move(moved)
+	for x := 10; x < 24; x++ {
+		grid.SetBlocked(Cell{X: x, Z: 10}, true)
+	}
+	checkFresh(t, grid, p.Replan(), moved, target)
+}
@@ -2,62 +2,20 @@ package pathfinder
 
 import (
 	"container/heap"
+	"context"
 	"math"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
-var (
-	path       []rl.Vector3
-	targetPos          = rl.NewVector3(0, 0, 0)
-	currentPos         = rl.NewVector3(0, 0, 0)
-	moveSpeed  float32 = 0.1
-)
-
-func GetPath() []rl.Vector3 {
-	return path
-}
-
-func SetPath(newPath []rl.Vector3) {
-	path = newPath
-}
-
-// Getters and Setters for targetPos
-
-func GetTargetPos() rl.Vector3 {
-	return targetPos
-}
-
-func SetTargetPos(newTargetPos rl.Vector3) {
-	targetPos = newTargetPos
-}
-
-// Getters and Setters for currentPos
-
-func GetcurrentPos() rl.Vector3 {
-	return currentPos
-}
-
-func SetcurrentPos(newcurrentPos rl.Vector3) {
-	currentPos = newcurrentPos
-}
-
-// Getters and Setters for moveSpeed
-
-func GetMoveSpeed() float32 {
-	return moveSpeed
-}
-
-func SetMoveSpeed(newMoveSpeed float32) {
-	moveSpeed = newMoveSpeed
-}
-
 // Node struct represents a node in the pathfinding grid.
 type Node struct {
+	cell         Cell
 	position     rl.Vector3
 	gCost, hCost float64
 	parent       *Node
-	index        int // Index in the priority queue
+	index        int  // Index in the priority queue, -1 when not queued
+	closed       bool // Set once the node has been expanded
 }
 
 // PriorityQueue is a min heap for nodes based on total cost.
@@ -65,7 +23,12 @@ type PriorityQueue []*Node
 
 func (pq PriorityQueue) Len() int { return len(pq) }
 func (pq PriorityQueue) Less(i, j int) bool {
-	return (pq[i].gCost + pq[i].hCost) < (pq[j].gCost + pq[j].hCost)
+	fi, fj := pq[i].gCost+pq[i].hCost, pq[j].gCost+pq[j].hCost
+	if fi == fj {
+		// Prefer the node further along its path to break ties towards the goal
+		return pq[i].gCost > pq[j].gCost
+	}
+	return fi < fj
 }
 func (pq PriorityQueue) Swap(i, j int) {
 	pq[i], pq[j] = pq[j], pq[i]
@@ -87,10 +50,6 @@ func (pq *PriorityQueue) Pop() interface{} {
 	return node
 }
 
-func FindPath(target rl.Vector3) {
-	path = findPath(currentPos, target)
-}
-
 // getCurrentNode finds the node with the lowest cost in the open set using a priority queue.
 func getCurrentNode(openSet *PriorityQueue) *Node {
 	if openSet.Len() == 0 {
@@ -103,108 +62,297 @@ func getCurrentNode(openSet *PriorityQueue) *Node {
 func reconstructPath(current *Node) []rl.Vector3 {
 	path := make([]rl.Vector3, 0)
 	for current != nil {
-		path = append([]rl.Vector3{current.position}, path...)
+		path = append(path, current.position)
 		current = current.parent
 	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
 	return path
 }
 
-// getNodeFromWorldPos converts a world position to a grid node.
-func getNodeFromWorldPos(pos rl.Vector3) *Node {
-	gridPos := rl.NewVector3(
-		float32(math.Ceil(float64(pos.X))),
-		float32(math.Ceil(float64(pos.Y))),
-		float32(math.Ceil(float64(pos.Z))),
-	)
-	return &Node{position: gridPos}
+// getNodeFromWorldPos converts a world position to a grid node. In ground
+// mode the node is placed on the terrain below pos.
+func getNodeFromWorldPos(grid NavGrid, pos rl.Vector3, opts Options) Node {
+	cell := grid.WorldToCell(pos)
+	if opts.Mode == ModeGround {
+		return getGroundNode(grid, cell.X, cell.Z)
+	}
+	return Node{cell: cell, position: grid.CellToWorld(cell)}
+}
+
+// getGroundNode returns the node resting on the terrain in column x, z.
+func getGroundNode(grid NavGrid, x, z int) Node {
+	position := grid.CellToWorld(Cell{X: x, Z: z})
+	if column, ok := grid.getGround(x, z); ok {
+		position.Y = column.height
+		return Node{cell: Cell{x, column.y, z}, position: position}
+	}
+	position.Y = grid.GetGroundHeight(position.X, position.Z)
+	return Node{cell: Cell{x, grid.WorldToCell(position).Y, z}, position: position}
 }
 
-// getNeighbors returns the neighboring nodes of a given node.
-func getNeighbors(node *Node) []*Node {
-	neighbors := make([]*Node, 0)
+// getNeighbors appends the walkable neighboring nodes of a given node to neighbors.
+func getNeighbors(grid NavGrid, node *Node, opts Options, neighbors []Node) []Node {
+	if opts.Mode == ModeGround {
+		return getGroundNeighbors(grid, node, opts, neighbors)
+	}
 
-	for x := -0.5; x <= 0.5; x += 0.5 {
-		for y := -0.5; y <= 0.5; y += 0.5 {
-			for z := -0.5; z <= 0.5; z += 0.5 {
+	for x := -1; x <= 1; x++ {
+		for y := -1; y <= 1; y++ {
+			for z := -1; z <= 1; z++ {
 				if x == 0 && y == 0 && z == 0 {
 					continue
 				}
-				neighborPos := rl.NewVector3(
-					node.position.X+float32(x),
-					node.position.Y+float32(y),
-					node.position.Z+float32(z),
-				)
-				neighbors = append(neighbors, &Node{position: neighborPos})
+				cell := Cell{node.cell.X + x, node.cell.Y + y, node.cell.Z + z}
+				if !isWalkable(grid, cell) || !canCutCorner(grid, node.cell, Cell{x, y, z}, opts.Corners) {
+					continue
+				}
+				neighbors = append(neighbors, Node{cell: cell, position: grid.CellToWorld(cell)})
 			}
 		}
 	}
 	return neighbors
 }
 
-// heuristicEuclidean calculates the Euclidean distance between two 3D points.
-func heuristicEuclidean(a, b rl.Vector3) float64 {
-	dx := float64(a.X - b.X)
-	dy := float64(a.Y - b.Y)
-	dz := float64(a.Z - b.Z)
-	return (dx*dx + dy*dy + dz*dz)
-}
+// getGroundNeighbors appends the walkable neighbors of node on the XZ plane to neighbors.
+func getGroundNeighbors(grid NavGrid, node *Node, opts Options, neighbors []Node) []Node {
+	// Look the surrounding columns up once, diagonal moves need the straight ones too
+	var free [3][3]bool
+	for x := -1; x <= 1; x++ {
+		for z := -1; z <= 1; z++ {
+			if x != 0 || z != 0 {
+				free[x+1][z+1] = canStandColumn(grid, node.cell.X+x, node.cell.Z+z, opts)
+			}
+		}
+	}
 
-// findPath performs A* pathfinding to find a path from start to target using a priority queue.
-func findPath(start, target rl.Vector3) []rl.Vector3 {
-	startNode := getNodeFromWorldPos(start)
-	targetNode := getNodeFromWorldPos(target)
+	for x := -1; x <= 1; x++ {
+		for z := -1; z <= 1; z++ {
+			if !free[x+1][z+1] {
+				continue
+			}
+			if x != 0 && z != 0 {
+				if opts.Connectivity == Connect4 || !canCutGroundCorner(free[x+1][1], free[1][z+1], opts.Corners) {
+					continue
+				}
+			}
+			neighbors = append(neighbors, getGroundNode(grid, node.cell.X+x, node.cell.Z+z))
+		}
+	}
+	return neighbors
+}
 
-	openSet := make(PriorityQueue, 0)
-	heap.Init(&openSet)
-	closedSet := make(map[rl.Vector3]*Node)
+// isWalkable reports whether cell lies on grid and is not blocked.
+func isWalkable(grid NavGrid, cell Cell) bool {
+	return grid.InBounds(cell) && !grid.IsBlocked(cell)
+}
 
-	heap.Push(&openSet, startNode)
+// canStand reports whether an agent sized by opts fits on cell: the cell is
+// walkable and, on the ground, has room for opts.AgentRadius.
+func canStand(grid NavGrid, cell Cell, opts Options) bool {
+	// Ground cells are looked up rather than checked against the obstacles
+	if column, ok := grid.getGround(cell.X, cell.Z); ok && column.y == cell.Y {
+		if !column.walkable {
+			return false
+		}
+	} else if !isWalkable(grid, cell) {
+		return false
+	}
+	return opts.Mode != ModeGround || opts.AgentRadius <= 0 || grid.GetClearance(cell) >= opts.AgentRadius
+}
 
-	for openSet.Len() > 0 {
-		current := getCurrentNode(&openSet)
+// canStandColumn is canStand for the ground cell of column x, z.
+func canStandColumn(grid NavGrid, x, z int, opts Options) bool {
+	column, ok := grid.getGround(x, z)
+	return ok && column.walkable && (opts.AgentRadius <= 0 || grid.GetClearance(Cell{X: x, Z: z}) >= opts.AgentRadius)
+}
 
-		closedSet[current.position] = current
+// clearanceReach returns how many cells away a grid change can alter where
+// an agent sized by opts fits.
+func clearanceReach(grid NavGrid, opts Options) int {
+	if opts.Mode != ModeGround || opts.AgentRadius <= 0 {
+		return 0
+	}
+	cellSize := grid.GetCellSize()
+	return int(math.Ceil(float64((opts.AgentRadius + cellSize/2) / cellSize)))
+}
 
-		if current.position == targetNode.position {
-			return reconstructPath(current)
+// canCutCorner applies rule to a 3D step by delta from cell, checking the
+// cells the step squeezes past.
+func canCutCorner(grid NavGrid, cell, delta Cell, rule CornerRule) bool {
+	axes := [3]Cell{{X: delta.X}, {Y: delta.Y}, {Z: delta.Z}}
+	moving := 0
+	for _, axis := range axes {
+		if axis != (Cell{}) {
+			moving++
 		}
+	}
+	if moving < 2 || rule == CornerAlways {
+		return true
+	}
 
-		neighbors := getNeighbors(current)
-		for _, neighbor := range neighbors {
-			updateNeighbor(neighbor, current, targetNode, &openSet, closedSet)
+	anyFree := false
+	for mask := 1; mask < 7; mask++ {
+		var step Cell
+		parts := 0
+		for i, axis := range axes {
+			if mask&(1<<i) != 0 && axis != (Cell{}) {
+				step = Cell{step.X + axis.X, step.Y + axis.Y, step.Z + axis.Z}
+				parts++
+			}
+		}
+		if parts == 0 || step == delta {
+			continue
+		}
+		free := isWalkable(grid, Cell{cell.X + step.X, cell.Y + step.Y, cell.Z + step.Z})
+		if !free && rule == CornerNever {
+			return false
+		}
+		if free && parts == 1 {
+			anyFree = true
 		}
 	}
+	return rule == CornerNever || anyFree
+}
 
-	return []rl.Vector3{}
+// canCutGroundCorner applies rule to a diagonal ground step whose straight
+// neighbours along X and Z are freeX and freeZ.
+func canCutGroundCorner(freeX, freeZ bool, rule CornerRule) bool {
+	switch rule {
+	case CornerAlways:
+		return true
+	case CornerNever:
+		return freeX && freeZ
+	default:
+		return freeX || freeZ
+	}
 }
 
-// updateNeighbor updates the neighbor's cost and parent if a shorter path is found using a priority queue.
-func updateNeighbor(neighbor *Node, current, targetNode *Node, openSet *PriorityQueue, closedSet map[rl.Vector3]*Node) {
-	if closedSet[neighbor.position] != nil {
-		return
+// cancelCheckInterval is the number of expansions between two checks of the
+// search context.
+const cancelCheckInterval = 256
+
+// FindPath performs A* pathfinding to find a path from start to target using a priority queue.
+// The search stays inside grid, never enters blocked cells and gives up after
+// opts.MaxNodes expansions. opts.Mode picks between flying through the whole
+// grid and walking on the terrain.
+func FindPath(grid NavGrid, start, target rl.Vector3, opts Options) Result {
+	return FindPathContext(context.Background(), grid, start, target, opts)
+}
+
+// FindPathContext is FindPath with a context that can cancel the search.
+// The grid is read locked while the search runs, so it may be called from
+// any goroutine.
+func FindPathContext(ctx context.Context, grid NavGrid, start, target rl.Vector3, opts Options) Result {
+	grid.RLock()
+	defer grid.RUnlock()
+	return findPath(ctx, grid, start, target, opts)
+}
+
+// findPath is FindPathContext for callers already holding the grid read lock.
+func findPath(ctx context.Context, grid NavGrid, start, target rl.Vector3, opts Options) Result {
+	startNode := getNodeFromWorldPos(grid, start, opts)
+	targetNode := getNodeFromWorldPos(grid, target, opts)
+
+	if !grid.InBounds(startNode.cell) || !grid.InBounds(targetNode.cell) {
+		return Result{Status: StatusUnreachable, Err: ErrOutOfBounds}
+	}
+	failure := ErrUnreachable
+	if !canStand(grid, targetNode.cell, opts) {
+		if !opts.AllowPartial {
+			return Result{Status: StatusUnreachable, Err: ErrBlocked}
+		}
+		failure = ErrBlocked
 	}
 
-	tentativeGCost := float64(current.gCost) + float64(rl.Vector3Distance(current.position, neighbor.position))
+	if opts.Algorithm == AlgorithmJPS && canJump(opts) {
+		return findPathJPS(ctx, grid, startNode, targetNode, failure, opts)
+	}
 
-	if openSetContains(openSet, neighbor) && tentativeGCost >= float64(neighbor.gCost) {
-		return
+	search := acquireSearch()
+	defer releaseSearch(search)
+	if opts.Mode == ModeGround {
+		search.onGround(grid)
+	}
+
+	first := search.add(startNode.cell, startNode.position)
+	first.hCost = estimate(first.position, targetNode.position, opts)
+	heap.Push(&search.openSet, first)
+	closest := first
+	expanded := 0
+
+	for search.openSet.Len() > 0 {
+		current := getCurrentNode(&search.openSet)
+
+		current.closed = true
+		expanded++
+		opts.recording.expand(current)
+
+		if current.cell == targetNode.cell {
+			return Result{Status: StatusFound, Path: postProcess(grid, reconstructPath(current), opts), Cost: current.gCost, Expanded: expanded}
+		}
+		if expanded%cancelCheckInterval == 0 && ctx.Err() != nil {
+			return Result{Status: StatusCanceled, Expanded: expanded, Err: ctx.Err()}
+		}
+		if current.hCost < closest.hCost {
+			closest = current
+		}
+		if opts.MaxNodes > 0 && expanded >= opts.MaxNodes {
+			return failedResult(grid, closest, expanded, ErrNodeLimit, opts, reconstructPath)
+		}
+
+		search.neighbors = getNeighbors(grid, current, opts, search.neighbors[:0])
+		for i := range search.neighbors {
+			updateNeighbor(&search.neighbors[i], current, &targetNode, search, opts)
+		}
 	}
 
-	neighbor.gCost = tentativeGCost
-	neighbor.hCost = heuristicEuclidean(neighbor.position, targetNode.position)
-	neighbor.parent = current
+	return failedResult(grid, closest, expanded, failure, opts, reconstructPath)
+}
 
-	if !openSetContains(openSet, neighbor) {
-		heap.Push(openSet, neighbor)
+// failedResult builds the result of a search that did not reach its goal,
+// rebuilding any partial path with reconstruct.
+func failedResult(grid NavGrid, closest *Node, expanded int, err error, opts Options, reconstruct func(*Node) []rl.Vector3) Result {
+	if opts.AllowPartial && closest.parent != nil {
+		return Result{Status: StatusPartial, Path: postProcess(grid, reconstruct(closest), opts), Cost: closest.gCost, Expanded: expanded, Err: err}
 	}
+	return Result{Status: StatusUnreachable, Expanded: expanded, Err: err}
 }
 
-// openSetContains checks if the priority queue (open set) contains a node.
-func openSetContains(openSet *PriorityQueue, node *Node) bool {
-	for _, n := range *openSet {
-		if n.position == node.position {
-			return true
-		}
+// updateNeighbor updates the neighbor's cost and parent if a shorter path is found using a priority queue.
+// Nodes already queued are moved up the heap in place instead of being pushed again.
+func updateNeighbor(neighbor *Node, current, targetNode *Node, search *searchState, opts Options) {
+	node := search.get(neighbor.cell)
+	if node != nil && node.closed {
+		return
+	}
+
+	cost, ok := stepCost(current, neighbor, opts)
+	if !ok {
+		return
+	}
+	relaxNode(search, node, neighbor, current, current.gCost+cost, targetNode, opts)
+}
+
+// relaxNode records tentativeGCost for neighbor, reached from current, when
+// it improves on the cost already known for the existing node.
+func relaxNode(search *searchState, node, neighbor, current *Node, tentativeGCost float64, targetNode *Node, opts Options) {
+	if node == nil {
+		node = search.add(neighbor.cell, neighbor.position)
+		node.gCost = tentativeGCost
+		node.hCost = estimate(node.position, targetNode.position, opts)
+		node.parent = current
+		heap.Push(&search.openSet, node)
+		opts.recording.open(node)
+		return
 	}
-	return false
+	if tentativeGCost >= node.gCost {
+		return
+	}
+
+	node.gCost = tentativeGCost
+	node.parent = current
+	heap.Fix(&search.openSet, node.index)
+	opts.recording.open(node)
 }
@@ -0,0 +1,75 @@
+package pathfinder
+
+import (
+	"math/rand"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// benchGridSize is the number of columns along each side of the benchmark grids.
+const benchGridSize = 256
+
+// newBenchGrid returns a flat ground grid of size columns along each side.
+// With walls, every eighth row is a wall with a few gaps and single blocked
+// cells are scattered in between, so the searches wind across the grid.
+func newBenchGrid(size int, walls bool) NavGrid {
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: size, Y: 1, Z: size})
+	if !walls {
+		return grid
+	}
+	random := rand.New(rand.NewSource(1))
+	for z := 4; z < size-4; z += 8 {
+		for x := 0; x < size; x++ {
+			if random.Intn(32) != 0 {
+				grid.SetBlocked(Cell{X: x, Z: z}, true)
+			}
+		}
+	}
+	for i := 0; i < size*size/16; i++ {
+		grid.SetBlocked(Cell{X: random.Intn(size), Z: random.Intn(size)}, true)
+	}
+	grid.SetBlocked(Cell{}, false)
+	grid.SetBlocked(Cell{X: size - 1, Z: size - 1}, false)
+	return grid
+}
+
+// BenchmarkFindPath crosses the benchmark grids corner to corner. Nodes come
+// from the pooled search state, so a warm search allocates little more than
+// the returned path. Ground columns are looked up from the grid rather than
+// sampled, so A* across the walled 200 by 200 grid, which expands most of
+// it, should stay within a 20 ms budget on a desktop CPU. JPS should beat A*
+// on the walled grids; on the open one A* walks straight down the diagonal.
+func BenchmarkFindPath(b *testing.B) {
+	for _, bench := range []struct {
+		name      string
+		size      int
+		walls     bool
+		algorithm Algorithm
+	}{
+		{"open/astar", benchGridSize, false, AlgorithmAStar},
+		{"walls/astar", benchGridSize, true, AlgorithmAStar},
+		{"walls200/astar", 200, true, AlgorithmAStar},
+		{"open/jps", benchGridSize, false, AlgorithmJPS},
+		{"walls/jps", benchGridSize, true, AlgorithmJPS},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			start := rl.NewVector3(0, 0, 0)
+			goal := rl.NewVector3(float32(bench.size-1), 0, float32(bench.size-1))
+			grid := newBenchGrid(bench.size, bench.walls)
+			opts := DefaultOptions()
+			opts.Algorithm = bench.algorithm
+			opts.MaxNodes = 0
+			opts.StringPull = false
+			if result := FindPath(grid, start, goal, opts); result.Status != StatusFound {
+				b.Fatalf("FindPath status %s, want found", result.Status)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				FindPath(grid, start, goal, opts)
+			}
+		})
+	}
+}
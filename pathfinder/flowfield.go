@@ -0,0 +1,299 @@
+package pathfinder
+
+import (
+	"container/heap"
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// FlowField steers any number of agents toward one shared goal on the
+// ground of a grid. It integrates the cost to the goal from every column
+// and points each column at the neighbour leading there, so sampling a
+// direction costs a single lookup.
+//
+// Building the field is spread over frames by Update. While a new goal or a
+// grid change is being integrated, agents keep steering with the last
+// complete field. A moved goal repairs the last field rather than building
+// a new one, a grid change builds it again. Like Hierarchy, a FlowField
+// belongs to the game loop, but the grid may be updated from any goroutine.
+type FlowField interface {
+	GetGoal() rl.Vector3
+	SetGoal(goal rl.Vector3)
+	Update(budget int) bool
+	IsReady() bool
+	GetCost(pos rl.Vector3) (float64, bool)
+	GetDirection(pos rl.Vector3) rl.Vector3
+	Close()
+}
+
+// flowLayer is one complete or partly integrated field.
+type flowLayer struct {
+	goal rl.Vector3
+	cost []float64
+	// next is the column to move to from each column, -1 at the goal and
+	// where the goal cannot be reached
+	next []int
+}
+
+type flowField struct {
+	grid    NavGrid
+	options Options
+	columns int
+	rows    int
+	goal    rl.Vector3
+
+	// ready is the last complete field, pending the one being integrated
+	ready   *flowLayer
+	pending *flowLayer
+	open    flowQueue
+	// dirty asks the next Update to start over, building is set while the
+	// open set of pending still holds work. stale marks a ready field older
+	// than the grid, which cannot be repaired. Grid updates set dirty and
+	// stale under the grid write lock, so they are only touched under the
+	// grid lock
+	dirty    bool
+	stale    bool
+	building bool
+	// base is the field a repair starts from, nil for a full build. Columns
+	// reach the new goal through the old one at their base cost plus
+	// offset, the cost from the old goal to the new one once it is known
+	base   *flowLayer
+	origin int
+	offset float64
+
+	neighbors   []Node
+	unsubscribe func()
+}
+
+// NewFlowField creates a new instance of FlowField over the ground of grid.
+// The field is empty until a goal is set and Update completes it.
+func NewFlowField(grid NavGrid, opts Options) FlowField {
+	opts.Mode = ModeGround
+	size := grid.GetSize()
+	f := &flowField{
+		grid:    grid,
+		options: opts,
+		columns: size.X,
+		rows:    size.Z,
+		pending: newFlowLayer(size.X * size.Z),
+	}
+	f.unsubscribe = grid.Subscribe(func(min, max Cell) { f.dirty, f.stale = true, true })
+	return f
+}
+
+func newFlowLayer(size int) *flowLayer {
+	return &flowLayer{cost: make([]float64, size), next: make([]int, size)}
+}
+
+// Getters and Setters for flowField
+func (f *flowField) GetGoal() rl.Vector3 {
+	return f.goal
+}
+
+// SetGoal makes the next Update start integrating toward goal. Moving the
+// goal within its column keeps the current field. Moving it to another
+// column repairs the last complete field: the integration starts again from
+// the new goal, but stops at every column that reaches the new goal as
+// cheaply through the old one, and those keep their direction. Without a
+// complete field, or after the grid changed, the field is built in full.
+func (f *flowField) SetGoal(goal rl.Vector3) {
+	f.grid.RLock()
+	defer f.grid.RUnlock()
+
+	previous := f.grid.WorldToCell(f.goal)
+	cell := f.grid.WorldToCell(goal)
+	f.goal = goal
+	if !f.dirty && (f.ready != nil || f.building) && cell.X == previous.X && cell.Z == previous.Z {
+		if f.ready != nil && !f.building {
+			f.ready.goal = goal
+		}
+		f.pending.goal = goal
+		return
+	}
+	f.dirty = true
+}
+
+// IsReady reports whether a complete field is available to steer with.
+func (f *flowField) IsReady() bool {
+	return f.ready != nil
+}
+
+// Close stops following changes to the grid.
+func (f *flowField) Close() {
+	if f.unsubscribe != nil {
+		f.unsubscribe()
+		f.unsubscribe = nil
+	}
+}
+
+// restart throws away the field being built and seeds a new one at the
+// goal, repairing the ready field when it matches the grid.
+func (f *flowField) restart() {
+	f.dirty = false
+	f.building = true
+	layer := f.pending
+	for i := range layer.cost {
+		layer.cost[i] = math.Inf(1)
+		layer.next[i] = -1
+	}
+	f.open = f.open[:0]
+
+	f.base, f.offset = nil, math.Inf(1)
+	if f.ready != nil && !f.stale {
+		cell := f.grid.WorldToCell(f.ready.goal)
+		if origin, ok := f.index(cell.X, cell.Z); ok && f.ready.cost[origin] == 0 {
+			f.base, f.origin = f.ready, origin
+		}
+	}
+
+	layer.goal = f.goal
+	cell := f.grid.WorldToCell(f.goal)
+	if index, ok := f.index(cell.X, cell.Z); ok && canStand(f.grid, getGroundNode(f.grid, cell.X, cell.Z).cell, f.options) {
+		layer.cost[index] = 0
+		heap.Push(&f.open, flowItem{index: index})
+	}
+}
+
+// Update integrates up to budget columns and reports whether the field is
+// complete. Call it once per frame until it returns true.
+func (f *flowField) Update(budget int) bool {
+	f.grid.RLock()
+	defer f.grid.RUnlock()
+
+	if f.dirty {
+		f.restart()
+	}
+	if !f.building {
+		return f.ready != nil
+	}
+	layer := f.pending
+	for expanded := 0; f.open.Len() > 0 && (budget <= 0 || expanded < budget); {
+		item := heap.Pop(&f.open).(flowItem)
+		if item.cost > layer.cost[item.index] {
+			continue // Stale entry, the column was reached more cheaply since
+		}
+		if f.base != nil {
+			if item.index == f.origin {
+				f.offset = item.cost
+			}
+			if item.cost >= f.base.cost[item.index]+f.offset {
+				continue // The way through the old goal is as short
+			}
+		}
+		expanded++
+
+		current := getGroundNode(f.grid, item.index%f.columns, item.index/f.columns)
+		f.neighbors = getGroundNeighbors(f.grid, &current, f.options, f.neighbors[:0])
+		for i := range f.neighbors {
+			neighbor := &f.neighbors[i]
+			// Agents walk toward the goal, so charge the step in that direction
+			cost, ok := stepCost(neighbor, &current, f.options)
+			if !ok {
+				continue
+			}
+			index, _ := f.index(neighbor.cell.X, neighbor.cell.Z)
+			if total := item.cost + cost; total < f.bound(index) {
+				layer.cost[index] = total
+				layer.next[index] = item.index
+				heap.Push(&f.open, flowItem{index: index, cost: total})
+			}
+		}
+	}
+	if f.open.Len() > 0 {
+		return false
+	}
+
+	// Columns left alone keep their way through the old goal
+	if f.base != nil {
+		for i, cost := range f.base.cost {
+			if shifted := cost + f.offset; shifted < layer.cost[i] {
+				layer.cost[i] = shifted
+				layer.next[i] = f.base.next[i]
+			}
+		}
+		f.base = nil
+	}
+
+	// Publish the finished field and recycle the old one for the next build
+	if f.ready == nil {
+		f.ready = newFlowLayer(len(layer.cost))
+	}
+	f.ready, f.pending = layer, f.ready
+	f.building, f.stale = false, false
+	return true
+}
+
+// bound returns the lowest cost known for the column at index in the field
+// being built, counting the way through the old goal of a repair.
+func (f *flowField) bound(index int) float64 {
+	if f.base == nil {
+		return f.pending.cost[index]
+	}
+	return math.Min(f.pending.cost[index], f.base.cost[index]+f.offset)
+}
+
+// GetCost returns the integrated cost from pos to the goal and whether the
+// goal can be reached from there.
+func (f *flowField) GetCost(pos rl.Vector3) (float64, bool) {
+	index, ok := f.sample(pos)
+	if !ok || math.IsInf(f.ready.cost[index], 1) {
+		return 0, false
+	}
+	return f.ready.cost[index], true
+}
+
+// GetDirection returns the unit direction on the X/Z plane an agent at pos
+// should move in, or a zero vector when the goal cannot be reached.
+func (f *flowField) GetDirection(pos rl.Vector3) rl.Vector3 {
+	index, ok := f.sample(pos)
+	if !ok || math.IsInf(f.ready.cost[index], 1) {
+		return rl.Vector3{}
+	}
+	var target rl.Vector3
+	if next := f.ready.next[index]; next >= 0 {
+		target = f.grid.CellToWorld(Cell{X: next % f.columns, Z: next / f.columns})
+	} else {
+		target = f.ready.goal
+	}
+	direction := rl.NewVector3(target.X-pos.X, 0, target.Z-pos.Z)
+	if rl.Vector3Length(direction) == 0 {
+		return rl.Vector3{}
+	}
+	return rl.Vector3Normalize(direction)
+}
+
+// sample returns the column of the ready field below pos.
+func (f *flowField) sample(pos rl.Vector3) (int, bool) {
+	if f.ready == nil {
+		return 0, false
+	}
+	cell := f.grid.WorldToCell(pos)
+	return f.index(cell.X, cell.Z)
+}
+
+func (f *flowField) index(x, z int) (int, bool) {
+	if x < 0 || z < 0 || x >= f.columns || z >= f.rows {
+		return 0, false
+	}
+	return z*f.columns + x, true
+}
+
+// flowItem is a column waiting in the open set with the cost it was queued at.
+type flowItem struct {
+	index int
+	cost  float64
+}
+
+type flowQueue []flowItem
+
+func (q flowQueue) Len() int           { return len(q) }
+func (q flowQueue) Less(i, j int) bool { return q[i].cost < q[j].cost }
+func (q flowQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *flowQueue) Push(x any)        { *q = append(*q, x.(flowItem)) }
+func (q *flowQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
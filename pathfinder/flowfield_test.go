@@ -0,0 +1,179 @@
+package pathfinder
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// buildField returns a complete flow field toward goal on grid and the
+// number of Update calls of budget one it took.
+func buildField(t testing.TB, grid NavGrid, goal rl.Vector3) (*flowField, int) {
+	f := NewFlowField(grid, DefaultOptions()).(*flowField)
+	f.SetGoal(goal)
+	return f, finishField(t, f)
+}
+
+// finishField updates f one column at a time until it is complete and
+// returns the number of calls.
+func finishField(t testing.TB, f *flowField) int {
+	t.Helper()
+	for calls := 1; ; calls++ {
+		if f.Update(1) {
+			return calls
+		}
+		if calls > 1<<20 {
+			t.Fatal("flow field never completed")
+		}
+	}
+}
+
+// checkField compares the ready field of f with one built from scratch and
+// follows the directions of every column to the goal.
+func checkField(t *testing.T, f *flowField) {
+	t.Helper()
+	want, _ := buildField(t, f.grid, f.GetGoal())
+	defer want.Close()
+	got := f.ready
+	goal := f.grid.WorldToCell(f.GetGoal())
+	for i := range got.cost {
+		if math.IsInf(want.ready.cost[i], 1) != math.IsInf(got.cost[i], 1) ||
+			math.Abs(want.ready.cost[i]-got.cost[i]) > 1e-9 {
+			t.Fatalf("column %d,%d: cost %.4f, want %.4f", i%f.columns, i/f.columns, got.cost[i], want.ready.cost[i])
+		}
+		if math.IsInf(got.cost[i], 1) {
+			continue
+		}
+		column := i
+		for steps := 0; got.next[column] >= 0; steps++ {
+			if steps > len(got.next) {
+				t.Fatalf("column %d,%d: directions loop", i%f.columns, i/f.columns)
+			}
+			column = got.next[column]
+		}
+		if column != goal.Z*f.columns+goal.X {
+			t.Fatalf("column %d,%d: directions end at %d,%d", i%f.columns, i/f.columns, column%f.columns, column/f.columns)
+		}
+	}
+}
+
+func TestFlowFieldSampling(t *testing.T) {
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 64, Y: 1, Z: 64})
+	f, _ := buildField(t, grid, rl.NewVector3(40, 0, 40))
+	defer f.Close()
+
+	pos := rl.NewVector3(3.2, 0, 7.9)
+	want, _ := f.GetCost(pos)
+	direction := f.GetDirection(pos)
+	if allocs := testing.AllocsPerRun(100, func() { f.GetDirection(pos); f.GetCost(pos) }); allocs != 0 {
+		t.Errorf("sampling allocates %.0f times", allocs)
+	}
+
+	// Until the new field is complete, agents keep the last one
+	f.SetGoal(rl.NewVector3(5, 0, 5))
+	f.Update(1)
+	if got, _ := f.GetCost(pos); got != want || f.GetDirection(pos) != direction {
+		t.Errorf("sampled the field being built, cost %.4f, want %.4f", got, want)
+	}
+	finishField(t, f)
+	if got, _ := f.GetCost(pos); got == want {
+		t.Errorf("still sampling the old field after the new one completed")
+	}
+}
+
+func TestFlowFieldMoveGoal(t *testing.T) {
+	// A dead end corridor along row 20, walled off from x = 20 on
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 48, Y: 1, Z: 48})
+	for x := 20; x < 46; x++ {
+		grid.SetBlocked(Cell{X: x, Z: 19}, true)
+		grid.SetBlocked(Cell{X: x, Z: 21}, true)
+	}
+	grid.SetBlocked(Cell{X: 45, Z: 20}, true)
+
+	f, full := buildField(t, grid, rl.NewVector3(21, 0, 20))
+	defer f.Close()
+	// Every column outside the corridor still walks through the old goal
+	f.SetGoal(rl.NewVector3(40, 0, 20))
+	if repair := finishField(t, f); repair > 30 {
+		t.Errorf("repair took %d updates, a full build %d", repair, full)
+	}
+	checkField(t, f)
+	f.SetGoal(rl.NewVector3(10, 0, 30))
+	finishField(t, f)
+	checkField(t, f)
+}
+
+func TestFlowFieldMoveGoalRandom(t *testing.T) {
+	random := rand.New(rand.NewSource(3))
+	for _, grid := range []NavGrid{newBenchGrid(benchGridSize, true), newTerraceGrid()} {
+		size := grid.GetSize()
+		goal := func() rl.Vector3 {
+			return grid.CellToWorld(Cell{X: random.Intn(size.X), Z: random.Intn(size.Z)})
+		}
+		f, _ := buildField(t, grid, goal())
+		for i := 0; i < 4; i++ {
+			f.SetGoal(goal())
+			finishField(t, f)
+			checkField(t, f)
+		}
+		f.Close()
+	}
+}
+
+func TestFlowFieldObstacles(t *testing.T) {
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 32, Y: 1, Z: 32})
+	f, _ := buildField(t, grid, rl.NewVector3(28, 0, 16))
+	defer f.Close()
+	pos := rl.NewVector3(4, 0, 16)
+	before, _ := f.GetCost(pos)
+
+	// A wall between the agent and the goal with a gap at the top
+	for z := 0; z < 30; z++ {
+		grid.SetBlocked(Cell{X: 16, Z: z}, true)
+	}
+	if got, _ := f.GetCost(pos); got != before {
+		t.Errorf("field changed before Update")
+	}
+	finishField(t, f)
+	checkField(t, f)
+	if after, _ := f.GetCost(pos); after <= before {
+		t.Errorf("cost %.4f around the wall, want more than %.4f", after, before)
+	}
+
+	// A goal moved after the wall went up must not reuse the field from before
+	grid.SetBlocked(Cell{X: 16, Z: 30}, true)
+	f.SetGoal(rl.NewVector3(28, 0, 4))
+	finishField(t, f)
+	checkField(t, f)
+	grid.SetBlocked(Cell{X: 16, Z: 30}, false)
+	f.SetGoal(rl.NewVector3(28, 0, 28))
+	finishField(t, f)
+	checkField(t, f)
+}
+
+// BenchmarkFlowFieldSample samples fields of growing sizes, which should
+// take the same time.
+func BenchmarkFlowFieldSample(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		size int
+	}{
+		{"64", 64},
+		{"512", 512},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: bench.size, Y: 1, Z: bench.size})
+			f := NewFlowField(grid, DefaultOptions())
+			defer f.Close()
+			f.SetGoal(rl.NewVector3(float32(bench.size/2), 0, float32(bench.size/2)))
+			f.Update(0)
+			pos := rl.NewVector3(1, 0, 1)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				f.GetDirection(pos)
+			}
+		})
+	}
+}
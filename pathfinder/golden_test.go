@@ -0,0 +1,232 @@
+package pathfinder
+
+import (
+	"encoding/json"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// update rewrites the .golden files instead of checking them:
+//
+//	go test ./pathfinder -run TestGolden -update
+var update = flag.Bool("update", false, "rewrite the .golden files of the golden tests")
+
+// goldenTolerance absorbs float32 rounding of waypoint positions.
+const goldenTolerance = 1e-4
+
+// goldenClusterSize is the cluster size of the HPA* configuration.
+const goldenClusterSize = 4
+
+// outcome is what a configuration produced on a map.
+type outcome struct {
+	Status    string  `json:"status"`
+	Waypoints int     `json:"waypoints"`
+	Length    float64 `json:"length"`
+	Cost      float64 `json:"cost"`
+	Expanded  int     `json:"expanded"`
+}
+
+// golden is the content of a .golden file.
+type golden struct {
+	// Optimal is the cheapest cost per connectivity, -1 when the goal is unreachable
+	Optimal  map[string]float64 `json:"optimal"`
+	Outcomes map[string]outcome `json:"outcomes"`
+}
+
+// goldenConfig is one way of solving a map.
+type goldenConfig struct {
+	name string
+	// connectivity names the optimal cost the configuration is held to
+	connectivity string
+	// optimal is set for searches that must return the cheapest path
+	optimal bool
+	solve   func(m *TextMap, opts Options) Result
+}
+
+// dijkstra turns A* into Dijkstra's algorithm.
+type dijkstra struct{}
+
+func (dijkstra) Estimate(a, b rl.Vector3) float64 {
+	return 0
+}
+
+var goldenConfigs = []goldenConfig{
+	{name: "astar8", connectivity: "8", optimal: true, solve: solveWith(nil)},
+	{name: "astar4", connectivity: "4", optimal: true, solve: solveWith(func(opts *Options) {
+		opts.Connectivity = Connect4
+		opts.Heuristic = Manhattan{}
+	})},
+	{name: "jps", connectivity: "8", optimal: true, solve: solveWith(jpsConfig)},
+	{name: "weighted", connectivity: "8", solve: solveWith(func(opts *Options) {
+		opts.Weight = 2
+	})},
+	{name: "stringpull", connectivity: "8", solve: solveWith(func(opts *Options) {
+		opts.StringPull = true
+	})},
+	{name: "hierarchy", connectivity: "8", solve: func(m *TextMap, opts Options) Result {
+		hierarchy := NewHierarchy(m.Grid, goldenClusterSize, opts)
+		defer hierarchy.Close()
+		return hierarchy.FindPath(m.Start, m.Goal)
+	}},
+}
+
+func jpsConfig(opts *Options) {
+	opts.Algorithm = AlgorithmJPS
+}
+
+// solveWith runs FindPath with the base options changed by configure.
+func solveWith(configure func(opts *Options)) func(m *TextMap, opts Options) Result {
+	return func(m *TextMap, opts Options) Result {
+		if configure != nil {
+			configure(&opts)
+		}
+		return FindPath(m.Grid, m.Start, m.Goal, opts)
+	}
+}
+
+// TestGolden solves every map in testdata with each configuration and
+// compares path status, waypoints, length, cost and expanded nodes with the
+// .golden file next to the map. A* and JPS must also match the optimal cost
+// found by Dijkstra's algorithm.
+func TestGolden(t *testing.T) {
+	maps, err := filepath.Glob("testdata/*.txt")
+	if err != nil || len(maps) == 0 {
+		t.Fatalf("no maps in testdata: %v", err)
+	}
+	for _, path := range maps {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".txt"), func(t *testing.T) {
+			testGoldenMap(t, path)
+		})
+	}
+}
+
+// testGoldenMap solves the map at path and checks it against, or writes,
+// its golden file.
+func testGoldenMap(t *testing.T, path string) {
+	m, err := LoadTextMap(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.StringPull = false
+	opts.MaxNodes = 0
+	opts.AllowPartial = false
+	opts.CostMap = m.Costs
+
+	got := golden{Optimal: map[string]float64{}, Outcomes: map[string]outcome{}}
+	for connectivity, value := range map[string]Connectivity{"8": Connect8, "4": Connect4} {
+		exhaustive := opts
+		exhaustive.Connectivity = value
+		exhaustive.Heuristic = dijkstra{}
+		got.Optimal[connectivity] = -1
+		if result := FindPath(m.Grid, m.Start, m.Goal, exhaustive); result.Status == StatusFound {
+			got.Optimal[connectivity] = round(result.Cost)
+		}
+	}
+
+	goldenPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".golden"
+	var want golden
+	if !*update {
+		data, err := os.ReadFile(goldenPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &want); err != nil {
+			t.Fatalf("decode %s: %v", goldenPath, err)
+		}
+		for connectivity, cost := range want.Optimal {
+			if math.Abs(got.Optimal[connectivity]-cost) > goldenTolerance {
+				t.Errorf("optimal %s-connected cost %.4f, golden %.4f", connectivity, got.Optimal[connectivity], cost)
+			}
+		}
+	}
+
+	for _, c := range goldenConfigs {
+		t.Run(c.name, func(t *testing.T) {
+			result := c.solve(m, opts)
+			got.Outcomes[c.name] = newOutcome(result)
+
+			optimal := got.Optimal[c.connectivity]
+			switch {
+			case optimal < 0 && result.Status == StatusFound:
+				t.Errorf("found a path to an unreachable goal")
+			case optimal >= 0 && result.Status != StatusFound:
+				t.Errorf("%s, want found", result.Status)
+			case optimal >= 0 && result.Cost < optimal-goldenTolerance:
+				t.Errorf("cost %.4f is below the optimal %.4f", result.Cost, optimal)
+			case optimal >= 0 && c.optimal && result.Cost > optimal+goldenTolerance:
+				t.Errorf("cost %.4f, optimal is %.4f", result.Cost, optimal)
+			}
+			if c.name == "jps" {
+				checkJPSFallback(t, m, opts, got.Outcomes)
+			}
+			if !*update {
+				compareOutcome(t, got.Outcomes[c.name], want.Outcomes[c.name])
+			}
+		})
+	}
+
+	if *update {
+		data, err := json.MarshalIndent(got, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(goldenPath, append(data, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkJPSFallback asserts that JPS jumps on maps without terrain costs and
+// falls back to the very same A* search on maps with them.
+func checkJPSFallback(t *testing.T, m *TextMap, opts Options, outcomes map[string]outcome) {
+	jpsConfig(&opts)
+	if jumps := canJump(opts); jumps != (m.Costs == nil) {
+		t.Fatalf("canJump = %v on a map with cost map %v", jumps, m.Costs != nil)
+	}
+	if m.Costs != nil && outcomes["jps"] != outcomes["astar8"] {
+		t.Errorf("fallback %+v differs from A* %+v", outcomes["jps"], outcomes["astar8"])
+	}
+}
+
+func newOutcome(result Result) outcome {
+	length := 0.0
+	for i := 1; i < len(result.Path); i++ {
+		length += float64(rl.Vector3Distance(result.Path[i-1], result.Path[i]))
+	}
+	return outcome{
+		Status:    result.Status.String(),
+		Waypoints: len(result.Path),
+		Length:    round(length),
+		Cost:      round(result.Cost),
+		Expanded:  result.Expanded,
+	}
+}
+
+// compareOutcome reports how got differs from want.
+func compareOutcome(t *testing.T, got, want outcome) {
+	t.Helper()
+	switch {
+	case got.Status != want.Status:
+		t.Errorf("status %s, golden %s", got.Status, want.Status)
+	case got.Waypoints != want.Waypoints:
+		t.Errorf("%d waypoints, golden %d", got.Waypoints, want.Waypoints)
+	case math.Abs(got.Length-want.Length) > goldenTolerance:
+		t.Errorf("length %.4f, golden %.4f", got.Length, want.Length)
+	case math.Abs(got.Cost-want.Cost) > goldenTolerance:
+		t.Errorf("cost %.4f, golden %.4f", got.Cost, want.Cost)
+	case got.Expanded != want.Expanded:
+		t.Errorf("expanded %d nodes, golden %d", got.Expanded, want.Expanded)
+	}
+}
+
+// round keeps golden files stable across float32 rounding differences.
+func round(v float64) float64 {
+	return math.Round(v*1e4) / 1e4
+}
@@ -0,0 +1,431 @@
+package pathfinder
+
+import (
+	"container/heap"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Cell is an integer coordinate on a NavGrid.
+type Cell struct {
+	X, Y, Z int
+}
+
+// HeightFunc returns the terrain height at world position x, z.
+type HeightFunc func(x, z float32) float32
+
+// ChangeFunc is called with the inclusive range of cells touched by a grid update.
+type ChangeFunc func(min, max Cell)
+
+// NavGrid is a voxel grid of walkable and blocked cells that the pathfinder
+// routes through. Updates take a write lock; code reading the grid from
+// another goroutine than the one updating it holds RLock while it reads.
+type NavGrid interface {
+	RLock()
+	RUnlock()
+	GetOrigin() rl.Vector3
+	GetCellSize() float32
+	GetSize() Cell
+	SetHeightFunc(heightFunc HeightFunc)
+	GetGroundHeight(x, z float32) float32
+	WorldToCell(pos rl.Vector3) Cell
+	CellToWorld(cell Cell) rl.Vector3
+	InBounds(cell Cell) bool
+	IsBlocked(cell Cell) bool
+	GetClearance(cell Cell) float32
+	SetBlocked(cell Cell, blocked bool)
+	AddObstacle(box rl.BoundingBox)
+	RemoveObstacle(box rl.BoundingBox)
+	Clear()
+	Subscribe(onChange ChangeFunc) (unsubscribe func())
+	getGround(x, z int) (groundColumn, bool)
+	getGroundMap() []groundColumn
+	getClearanceMap() []float32
+}
+
+// groundColumn is the ground cell of a column as the searches see it.
+type groundColumn struct {
+	y      int
+	height float32
+	// walkable is set when the ground cell lies on the grid and is not blocked
+	walkable bool
+	// flat is set when the eight columns around lie at the same height
+	flat bool
+}
+
+type navGrid struct {
+	mu       sync.RWMutex
+	origin   rl.Vector3
+	cellSize float32
+	size     Cell
+	height   HeightFunc
+	// blocked counts the obstacles covering each cell so that overlapping
+	// obstacles can be removed independently.
+	blocked   []uint16
+	listeners map[int]ChangeFunc
+	nextID    int
+
+	// clearance holds the room around each ground column, rebuilt by the
+	// first read after an update
+	clearance      []float32
+	clearanceValid atomic.Bool
+	clearanceMu    sync.Mutex
+
+	// ground holds the ground cell of each column, rebuilt by the first read
+	// after the terrain changes and kept up to date by blocking updates
+	ground      []groundColumn
+	groundValid atomic.Bool
+	groundMu    sync.Mutex
+}
+
+// NewNavGrid creates a new instance of NavGrid whose cell (0, 0, 0) is
+// centred on origin
+func NewNavGrid(origin rl.Vector3, cellSize float32, size Cell) NavGrid {
+	return &navGrid{
+		origin:    origin,
+		cellSize:  cellSize,
+		size:      size,
+		blocked:   make([]uint16, size.X*size.Y*size.Z),
+		listeners: make(map[int]ChangeFunc),
+	}
+}
+
+// NewNavGridFromBounds creates a NavGrid covering the box between min and max
+func NewNavGridFromBounds(min, max rl.Vector3, cellSize float32) NavGrid {
+	size := Cell{
+		X: int(math.Round(float64((max.X-min.X)/cellSize))) + 1,
+		Y: int(math.Round(float64((max.Y-min.Y)/cellSize))) + 1,
+		Z: int(math.Round(float64((max.Z-min.Z)/cellSize))) + 1,
+	}
+	return NewNavGrid(min, cellSize, size)
+}
+
+func (g *navGrid) RLock() {
+	g.mu.RLock()
+}
+
+func (g *navGrid) RUnlock() {
+	g.mu.RUnlock()
+}
+
+func (g *navGrid) GetOrigin() rl.Vector3 {
+	return g.origin
+}
+
+func (g *navGrid) GetCellSize() float32 {
+	return g.cellSize
+}
+
+func (g *navGrid) GetSize() Cell {
+	return g.size
+}
+
+func (g *navGrid) SetHeightFunc(heightFunc HeightFunc) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.height = heightFunc
+	g.groundValid.Store(false)
+	g.notifyAll()
+}
+
+// GetGroundHeight samples the terrain, which is flat at the grid origin
+// unless a HeightFunc has been set.
+func (g *navGrid) GetGroundHeight(x, z float32) float32 {
+	if g.height == nil {
+		return g.origin.Y
+	}
+	return g.height(x, z)
+}
+
+// WorldToCell returns the cell whose centre is closest to pos.
+func (g *navGrid) WorldToCell(pos rl.Vector3) Cell {
+	return Cell{
+		X: int(math.Round(float64((pos.X - g.origin.X) / g.cellSize))),
+		Y: int(math.Round(float64((pos.Y - g.origin.Y) / g.cellSize))),
+		Z: int(math.Round(float64((pos.Z - g.origin.Z) / g.cellSize))),
+	}
+}
+
+// CellToWorld returns the world position of the centre of cell.
+func (g *navGrid) CellToWorld(cell Cell) rl.Vector3 {
+	return rl.NewVector3(
+		g.origin.X+float32(cell.X)*g.cellSize,
+		g.origin.Y+float32(cell.Y)*g.cellSize,
+		g.origin.Z+float32(cell.Z)*g.cellSize,
+	)
+}
+
+func (g *navGrid) InBounds(cell Cell) bool {
+	return cell.X >= 0 && cell.X < g.size.X &&
+		cell.Y >= 0 && cell.Y < g.size.Y &&
+		cell.Z >= 0 && cell.Z < g.size.Z
+}
+
+// IsBlocked reports whether an obstacle covers cell. Cells outside the grid
+// are never blocked.
+func (g *navGrid) IsBlocked(cell Cell) bool {
+	if !g.InBounds(cell) {
+		return false
+	}
+	return g.blocked[g.index(cell)] > 0
+}
+
+// GetClearance returns the distance from the centre of the ground cell in
+// the column of cell to the nearest blocked ground cell or the edge of the
+// grid, 0 when the ground cell itself is blocked. Like IsBlocked it reads
+// the grid, so other goroutines than the updating one hold RLock.
+func (g *navGrid) GetClearance(cell Cell) float32 {
+	if cell.X < 0 || cell.Z < 0 || cell.X >= g.size.X || cell.Z >= g.size.Z {
+		return 0
+	}
+	if !g.clearanceValid.Load() {
+		g.updateClearance()
+	}
+	return g.clearance[cell.Z*g.size.X+cell.X]
+}
+
+func (g *navGrid) SetBlocked(cell Cell, blocked bool) {
+	if !g.InBounds(cell) {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if blocked == (g.blocked[g.index(cell)] > 0) {
+		return
+	}
+	if blocked {
+		g.blocked[g.index(cell)] = 1
+	} else {
+		g.blocked[g.index(cell)] = 0
+	}
+	g.notify(cell, cell)
+}
+
+// AddObstacle blocks every cell touched by box.
+func (g *navGrid) AddObstacle(box rl.BoundingBox) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	lo, hi := g.cellRange(box)
+	for x := lo.X; x <= hi.X; x++ {
+		for y := lo.Y; y <= hi.Y; y++ {
+			for z := lo.Z; z <= hi.Z; z++ {
+				g.blocked[g.index(Cell{x, y, z})]++
+			}
+		}
+	}
+	g.notify(lo, hi)
+}
+
+// RemoveObstacle releases the cells blocked by a previous AddObstacle with the same box.
+func (g *navGrid) RemoveObstacle(box rl.BoundingBox) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	lo, hi := g.cellRange(box)
+	for x := lo.X; x <= hi.X; x++ {
+		for y := lo.Y; y <= hi.Y; y++ {
+			for z := lo.Z; z <= hi.Z; z++ {
+				if i := g.index(Cell{x, y, z}); g.blocked[i] > 0 {
+					g.blocked[i]--
+				}
+			}
+		}
+	}
+	g.notify(lo, hi)
+}
+
+func (g *navGrid) Clear() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i := range g.blocked {
+		g.blocked[i] = 0
+	}
+	g.notifyAll()
+}
+
+// Subscribe registers onChange to be called after every update of the grid.
+// onChange runs while the grid is write locked and must not read it back.
+// Calling the returned function removes the subscription.
+func (g *navGrid) Subscribe(onChange ChangeFunc) func() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id := g.nextID
+	g.nextID++
+	g.listeners[id] = onChange
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		delete(g.listeners, id)
+	}
+}
+
+func (g *navGrid) notify(min, max Cell) {
+	if min.X > max.X || min.Y > max.Y || min.Z > max.Z {
+		return
+	}
+	g.clearanceValid.Store(false)
+	if g.groundValid.Load() {
+		for z := min.Z; z <= max.Z; z++ {
+			for x := min.X; x <= max.X; x++ {
+				column := &g.ground[z*g.size.X+x]
+				column.walkable = isWalkable(g, Cell{x, column.y, z})
+			}
+		}
+	}
+	for _, onChange := range g.listeners {
+		onChange(min, max)
+	}
+}
+
+func (g *navGrid) notifyAll() {
+	g.notify(Cell{}, Cell{g.size.X - 1, g.size.Y - 1, g.size.Z - 1})
+}
+
+// getGround returns the ground cell of column x, z, or false outside the
+// grid. Like IsBlocked it reads the grid, so other goroutines than the
+// updating one hold RLock.
+func (g *navGrid) getGround(x, z int) (groundColumn, bool) {
+	if x < 0 || z < 0 || x >= g.size.X || z >= g.size.Z {
+		return groundColumn{}, false
+	}
+	if !g.groundValid.Load() {
+		g.updateGround()
+	}
+	return g.ground[z*g.size.X+x], true
+}
+
+// getGroundMap returns the ground cell of every column, row by row along X.
+// The slice only changes under the write lock, so searches holding RLock
+// may scan it directly.
+func (g *navGrid) getGroundMap() []groundColumn {
+	if !g.groundValid.Load() {
+		g.updateGround()
+	}
+	return g.ground
+}
+
+// getClearanceMap returns the clearance of every column like getGroundMap.
+func (g *navGrid) getClearanceMap() []float32 {
+	if !g.clearanceValid.Load() {
+		g.updateClearance()
+	}
+	return g.clearance
+}
+
+// updateGround samples the terrain of every column once. Concurrent readers
+// wait for a single rebuild.
+func (g *navGrid) updateGround() {
+	g.groundMu.Lock()
+	defer g.groundMu.Unlock()
+	if g.groundValid.Load() {
+		return
+	}
+	columns, rows := g.size.X, g.size.Z
+	if len(g.ground) != columns*rows {
+		g.ground = make([]groundColumn, columns*rows)
+	}
+
+	// Heights include a border of columns just outside the grid
+	stride := columns + 2
+	heights := make([]float32, stride*(rows+2))
+	for z := -1; z <= rows; z++ {
+		for x := -1; x <= columns; x++ {
+			position := g.CellToWorld(Cell{X: x, Z: z})
+			heights[(z+1)*stride+x+1] = g.GetGroundHeight(position.X, position.Z)
+		}
+	}
+	for z := 0; z < rows; z++ {
+		for x := 0; x < columns; x++ {
+			i := (z+1)*stride + x + 1
+			column := groundColumn{height: heights[i], flat: true}
+			column.y = g.WorldToCell(rl.Vector3{Y: column.height}).Y
+			column.walkable = isWalkable(g, Cell{x, column.y, z})
+			for _, j := range [8]int{i - stride - 1, i - stride, i - stride + 1, i - 1, i + 1, i + stride - 1, i + stride, i + stride + 1} {
+				column.flat = column.flat && heights[j] == column.height
+			}
+			g.ground[z*columns+x] = column
+		}
+	}
+	g.groundValid.Store(true)
+}
+
+func (g *navGrid) index(cell Cell) int {
+	return (cell.Y*g.size.Z+cell.Z)*g.size.X + cell.X
+}
+
+// cellRange returns the inclusive range of in-bounds cells touched by box.
+func (g *navGrid) cellRange(box rl.BoundingBox) (Cell, Cell) {
+	lo := g.WorldToCell(box.Min)
+	hi := g.WorldToCell(box.Max)
+	lo = Cell{max(lo.X, 0), max(lo.Y, 0), max(lo.Z, 0)}
+	hi = Cell{min(hi.X, g.size.X-1), min(hi.Y, g.size.Y-1), min(hi.Z, g.size.Z-1)}
+	return lo, hi
+}
+
+// updateClearance spreads the nearest blocked ground cell from column to
+// column, starting with the cells just outside the grid. Concurrent readers
+// wait for a single rebuild.
+func (g *navGrid) updateClearance() {
+	g.clearanceMu.Lock()
+	defer g.clearanceMu.Unlock()
+	if g.clearanceValid.Load() {
+		return
+	}
+	columns, rows := g.size.X, g.size.Z
+	if len(g.clearance) != columns*rows {
+		g.clearance = make([]float32, columns*rows)
+	}
+
+	// Squared distances in cells to the nearest source keep the spreading exact
+	nearest := make([][2]int, columns*rows)
+	distance := make([]float64, columns*rows)
+	squared := func(x, z int, source [2]int) float64 {
+		dx, dz := float64(x-source[0]), float64(z-source[1])
+		return dx*dx + dz*dz
+	}
+	open := make(flowQueue, 0, columns*rows)
+	for z := 0; z < rows; z++ {
+		for x := 0; x < columns; x++ {
+			i := z*columns + x
+			nearest[i] = [2]int{x, z}
+			if isWalkable(g, getGroundNode(g, x, z).cell) {
+				distance[i] = math.Inf(1)
+				for _, edge := range [4][2]int{{-1, z}, {columns, z}, {x, -1}, {x, rows}} {
+					if d := squared(x, z, edge); d < distance[i] {
+						nearest[i], distance[i] = edge, d
+					}
+				}
+			}
+			open = append(open, flowItem{index: i, cost: distance[i]})
+		}
+	}
+	heap.Init(&open)
+	for open.Len() > 0 {
+		item := heap.Pop(&open).(flowItem)
+		if item.cost > distance[item.index] {
+			continue
+		}
+		x, z := item.index%columns, item.index/columns
+		for dz := -1; dz <= 1; dz++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, nz := x+dx, z+dz
+				if nx < 0 || nz < 0 || nx >= columns || nz >= rows {
+					continue
+				}
+				j := nz*columns + nx
+				if d := squared(nx, nz, nearest[item.index]); d < distance[j] {
+					nearest[j], distance[j] = nearest[item.index], d
+					heap.Push(&open, flowItem{index: j, cost: d})
+				}
+			}
+		}
+	}
+
+	// Measure to the side of the nearest cell rather than its centre
+	for i, d := range distance {
+		g.clearance[i] = max(float32(math.Sqrt(d))*g.cellSize-g.cellSize/2, 0)
+	}
+	g.clearanceValid.Store(true)
+}
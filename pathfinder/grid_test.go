@@ -0,0 +1,60 @@
+package pathfinder
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// checkGround compares the ground columns looked up from grid with the
+// terrain and obstacles sampled directly.
+func checkGround(t *testing.T, grid NavGrid) {
+	t.Helper()
+	size := grid.GetSize()
+	for z := 0; z < size.Z; z++ {
+		for x := 0; x < size.X; x++ {
+			column, ok := grid.getGround(x, z)
+			position := grid.CellToWorld(Cell{X: x, Z: z})
+			position.Y = grid.GetGroundHeight(position.X, position.Z)
+			cell := Cell{x, grid.WorldToCell(position).Y, z}
+			if !ok || column.height != position.Y || column.y != cell.Y || column.walkable != isWalkable(grid, cell) {
+				t.Fatalf("column %d,%d: got %+v, want height %.2f on %v walkable %t",
+					x, z, column, position.Y, cell, isWalkable(grid, cell))
+			}
+		}
+	}
+}
+
+func TestGroundColumns(t *testing.T) {
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 16, Y: 4, Z: 16})
+	checkGround(t, grid)
+
+	grid.SetBlocked(Cell{X: 3, Z: 4}, true)
+	grid.AddObstacle(rl.NewBoundingBox(rl.NewVector3(6, 0, 6), rl.NewVector3(9, 2, 8)))
+	checkGround(t, grid)
+
+	// Raising the terrain moves the ground cells above the blocked ones
+	grid.SetHeightFunc(func(x, z float32) float32 {
+		if x >= 8 {
+			return 1
+		}
+		return 0
+	})
+	checkGround(t, grid)
+	if column, _ := grid.getGround(8, 7); column.walkable != !grid.IsBlocked(Cell{8, 1, 7}) {
+		t.Errorf("raised column reports the cell below the terrain")
+	}
+	if column, _ := grid.getGround(7, 0); column.flat {
+		t.Errorf("column next to a step reports flat ground")
+	}
+	if column, _ := grid.getGround(2, 2); !column.flat {
+		t.Errorf("column on level ground reports a step")
+	}
+
+	grid.RemoveObstacle(rl.NewBoundingBox(rl.NewVector3(6, 0, 6), rl.NewVector3(9, 2, 8)))
+	grid.Clear()
+	checkGround(t, grid)
+	if _, ok := grid.getGround(16, 0); ok {
+		t.Errorf("column outside the grid was found")
+	}
+}
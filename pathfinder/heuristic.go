@@ -0,0 +1,128 @@
+package pathfinder
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Heuristic estimates the cost of travelling between two positions. An
+// estimate that never exceeds the real cost keeps A* paths optimal.
+type Heuristic interface {
+	Estimate(a, b rl.Vector3) float64
+}
+
+// CostFunc returns the cost of stepping from one cell to a neighbouring cell
+// distance world units away. A negative or infinite cost forbids the step.
+type CostFunc func(from, to Cell, distance float64) float64
+
+// Euclidean is the straight line distance, admissible for any movement.
+type Euclidean struct{}
+
+// Manhattan sums the distance along each axis, exact for 4-connected ground movement.
+type Manhattan struct{}
+
+// Octile charges diagonal steps at their true length, exact for 8- and 26-connected movement.
+type Octile struct{}
+
+// Chebyshev is the largest distance along any axis, for movement where diagonals cost the same as straight steps.
+type Chebyshev struct{}
+
+func (Euclidean) Estimate(a, b rl.Vector3) float64 {
+	dx, dy, dz := deltas(a, b)
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+func (Manhattan) Estimate(a, b rl.Vector3) float64 {
+	dx, dy, dz := deltas(a, b)
+	return dx + dy + dz
+}
+
+func (Octile) Estimate(a, b rl.Vector3) float64 {
+	dx, dy, dz := deltas(a, b)
+	// Sort so that dx <= dy <= dz, then walk the shortest axis on full
+	// diagonals, the middle one on plane diagonals and the rest straight.
+	if dx > dy {
+		dx, dy = dy, dx
+	}
+	if dy > dz {
+		dy, dz = dz, dy
+	}
+	if dx > dy {
+		dx, dy = dy, dx
+	}
+	return (math.Sqrt(3)-math.Sqrt(2))*dx + (math.Sqrt(2)-1)*dy + dz
+}
+
+func (Chebyshev) Estimate(a, b rl.Vector3) float64 {
+	dx, dy, dz := deltas(a, b)
+	return math.Max(dx, math.Max(dy, dz))
+}
+
+// deltas returns the absolute distance between a and b along each axis.
+func deltas(a, b rl.Vector3) (float64, float64, float64) {
+	return math.Abs(float64(a.X - b.X)), math.Abs(float64(a.Y - b.Y)), math.Abs(float64(a.Z - b.Z))
+}
+
+// estimate applies the heuristic and weight configured in opts.
+func estimate(a, b rl.Vector3, opts Options) float64 {
+	heuristic := opts.Heuristic
+	if heuristic == nil {
+		heuristic = Euclidean{}
+	}
+	weight := opts.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+	return weight * heuristic.Estimate(a, b)
+}
+
+// stepCost returns the cost of moving between two neighbouring nodes and
+// whether the move is allowed. A cost map charges half of the step at the
+// cost of each cell, and ground steps pay extra for the height they climb.
+func stepCost(from, to *Node, opts Options) (float64, bool) {
+	if !canStep(from.position, to.position, opts) {
+		return 0, false
+	}
+	climb := 0.0
+	if rise := float64(to.position.Y - from.position.Y); opts.Mode == ModeGround && rise > 0 {
+		climb = opts.ClimbCost * rise
+	}
+	distance := float64(rl.Vector3Distance(from.position, to.position))
+	if opts.CostMap != nil {
+		fromCost := opts.CostMap.GetCost(from.cell.X, from.cell.Z)
+		toCost := opts.CostMap.GetCost(to.cell.X, to.cell.Z)
+		if fromCost < 0 || toCost < 0 {
+			return 0, false
+		}
+		distance *= float64(fromCost+toCost) / 2
+	}
+	if opts.Cost == nil {
+		return distance + climb, true
+	}
+	cost := opts.Cost(from.cell, to.cell, distance)
+	if cost < 0 || math.IsInf(cost, 1) || math.IsNaN(cost) {
+		return 0, false
+	}
+	return cost + climb, true
+}
+
+// canStep reports whether a ground agent may step between two positions on
+// the terrain without exceeding the step height and slope limits of opts.
+func canStep(from, to rl.Vector3, opts Options) bool {
+	if opts.Mode != ModeGround {
+		return true
+	}
+	rise := math.Abs(float64(to.Y - from.Y))
+	if rise == 0 {
+		return true
+	}
+	if opts.MaxStepHeight > 0 && rise > float64(opts.MaxStepHeight) {
+		return false
+	}
+	if opts.MaxSlope > 0 {
+		run := math.Hypot(float64(to.X-from.X), float64(to.Z-from.Z))
+		return rise <= run*math.Tan(float64(opts.MaxSlope)*math.Pi/180)
+	}
+	return true
+}
@@ -0,0 +1,523 @@
+package pathfinder
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"sort"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// entranceSplit is the width of a border opening from which two transitions,
+// one at each end, are placed instead of a single one in the middle.
+const entranceSplit = 6
+
+// Hierarchy answers ground queries on large grids with HPA*. The grid is cut
+// into square clusters, the walkable openings between neighbouring clusters
+// become nodes of a small abstract graph, and a query is solved on that
+// graph before being refined into cells inside each cluster it crosses.
+//
+// A Hierarchy belongs to the game loop: queries rebuild the dirty clusters
+// in place, so FindPath, UpdateRegion and Rebuild must never run
+// concurrently. The grid may still be updated from any goroutine, the
+// clusters it marks dirty are only touched under the grid lock.
+type Hierarchy interface {
+	FindPath(start, target rl.Vector3) Result
+	GetClusterSize() int
+	UpdateRegion(min, max Cell)
+	Rebuild()
+	Close()
+}
+
+// entrance is a pair of facing walkable cells on either side of a cluster border.
+type entrance struct {
+	inside, outside Cell
+}
+
+// abstractEdge links two entrance cells of the abstract graph.
+type abstractEdge struct {
+	from, to Cell
+	cost     float64
+}
+
+type hierarchy struct {
+	grid        NavGrid
+	opts        Options
+	clusterSize int
+	clusters    Cell // Number of clusters along X and Z
+	// borders holds the entrances between each pair of neighbouring clusters
+	borders map[[2]int][]entrance
+	// intra holds the edges between the entrances of each cluster
+	intra map[int][]abstractEdge
+	graph map[Cell][]abstractEdge
+	// dirty is marked by grid updates under its write lock, everything else
+	// touches it under its read lock
+	dirty       map[int]bool
+	unsubscribe func()
+}
+
+// NewHierarchy creates a new instance of Hierarchy over grid with clusters
+// of clusterSize by clusterSize cells, at least one. opts is used for every query and for
+// the searches inside clusters, always in ground mode. The hierarchy keeps
+// itself up to date with changes to grid until Close is called.
+func NewHierarchy(grid NavGrid, clusterSize int, opts Options) Hierarchy {
+	opts.Mode = ModeGround
+	clusterSize = max(clusterSize, 1)
+	size := grid.GetSize()
+	h := &hierarchy{
+		grid:        grid,
+		opts:        opts,
+		clusterSize: clusterSize,
+		clusters:    Cell{X: (size.X + clusterSize - 1) / clusterSize, Z: (size.Z + clusterSize - 1) / clusterSize},
+		borders:     make(map[[2]int][]entrance),
+		intra:       make(map[int][]abstractEdge),
+		dirty:       make(map[int]bool),
+	}
+	h.Rebuild()
+	h.unsubscribe = grid.Subscribe(h.markRegion)
+	return h
+}
+
+func (h *hierarchy) GetClusterSize() int {
+	return h.clusterSize
+}
+
+// UpdateRegion marks the clusters around the changed cells for rebuilding
+// before the next query. Openings on a border depend on cells on both sides,
+// so the range is grown by one cell, plus the cells whose clearance it may
+// change for a sized agent.
+func (h *hierarchy) UpdateRegion(min, max Cell) {
+	h.grid.RLock()
+	defer h.grid.RUnlock()
+	h.markRegion(min, max)
+}
+
+// markRegion marks the clusters around a change while the grid is locked.
+// The grid calls it with the write lock held, so it must not lock the grid
+// itself.
+func (h *hierarchy) markRegion(min, max Cell) {
+	reach := 1 + clearanceReach(h.grid, h.opts)
+	lo := h.clusterOf(Cell{X: min.X - reach, Z: min.Z - reach})
+	hi := h.clusterOf(Cell{X: max.X + reach, Z: max.Z + reach})
+	for cz := lo.Z; cz <= hi.Z; cz++ {
+		for cx := lo.X; cx <= hi.X; cx++ {
+			h.dirty[cz*h.clusters.X+cx] = true
+		}
+	}
+}
+
+// Rebuild recomputes every cluster at once.
+func (h *hierarchy) Rebuild() {
+	h.grid.RLock()
+	defer h.grid.RUnlock()
+	for c := 0; c < h.clusters.X*h.clusters.Z; c++ {
+		h.dirty[c] = true
+	}
+	h.refresh()
+}
+
+func (h *hierarchy) Close() {
+	if h.unsubscribe != nil {
+		h.unsubscribe()
+		h.unsubscribe = nil
+	}
+}
+
+// clusterOf returns the cluster coordinates of cell, clamped to the grid.
+func (h *hierarchy) clusterOf(cell Cell) Cell {
+	return Cell{
+		X: min(max(cell.X, 0)/h.clusterSize, h.clusters.X-1),
+		Z: min(max(cell.Z, 0)/h.clusterSize, h.clusters.Z-1),
+	}
+}
+
+// clusterIndex returns the index of the cluster holding cell.
+func (h *hierarchy) clusterIndex(cell Cell) int {
+	c := h.clusterOf(cell)
+	return c.Z*h.clusters.X + c.X
+}
+
+// clusterBounds returns the inclusive cell range covered by a cluster.
+func (h *hierarchy) clusterBounds(cluster int) (Cell, Cell) {
+	size := h.grid.GetSize()
+	lo := Cell{X: cluster % h.clusters.X * h.clusterSize, Z: cluster / h.clusters.X * h.clusterSize}
+	hi := Cell{X: min(lo.X+h.clusterSize, size.X) - 1, Z: min(lo.Z+h.clusterSize, size.Z) - 1}
+	return lo, hi
+}
+
+// refresh rebuilds the borders and edges of dirty clusters and relinks the
+// abstract graph.
+func (h *hierarchy) refresh() {
+	if len(h.dirty) == 0 {
+		return
+	}
+
+	affected := make(map[int]bool)
+	for cluster := range h.dirty {
+		affected[cluster] = true
+		cx, cz := cluster%h.clusters.X, cluster/h.clusters.X
+		if cx+1 < h.clusters.X {
+			h.buildBorder(cluster, cluster+1, true)
+			affected[cluster+1] = true
+		}
+		if cx > 0 {
+			h.buildBorder(cluster-1, cluster, true)
+			affected[cluster-1] = true
+		}
+		if cz+1 < h.clusters.Z {
+			h.buildBorder(cluster, cluster+h.clusters.X, false)
+			affected[cluster+h.clusters.X] = true
+		}
+		if cz > 0 {
+			h.buildBorder(cluster-h.clusters.X, cluster, false)
+			affected[cluster-h.clusters.X] = true
+		}
+	}
+	for cluster := range affected {
+		h.buildIntraEdges(cluster)
+	}
+	clear(h.dirty)
+
+	h.graph = make(map[Cell][]abstractEdge)
+	for _, entrances := range h.borders {
+		for _, e := range entrances {
+			inside := getGroundNode(h.grid, e.inside.X, e.inside.Z)
+			outside := getGroundNode(h.grid, e.outside.X, e.outside.Z)
+			if cost, ok := stepCost(&inside, &outside, h.opts); ok {
+				h.graph[e.inside] = append(h.graph[e.inside], abstractEdge{e.inside, e.outside, cost})
+			}
+			if cost, ok := stepCost(&outside, &inside, h.opts); ok {
+				h.graph[e.outside] = append(h.graph[e.outside], abstractEdge{e.outside, e.inside, cost})
+			}
+		}
+	}
+	for _, edges := range h.intra {
+		for _, edge := range edges {
+			h.graph[edge.from] = append(h.graph[edge.from], edge)
+		}
+	}
+}
+
+// buildBorder finds the openings between cluster a and the cluster b next to
+// it along X when horizontal is set, along Z otherwise.
+func (h *hierarchy) buildBorder(a, b int, horizontal bool) {
+	lo, hi := h.clusterBounds(a)
+	var entrances []entrance
+	var run []entrance
+	flush := func() {
+		switch {
+		case len(run) == 0:
+		case len(run) < entranceSplit:
+			entrances = append(entrances, run[len(run)/2])
+		default:
+			entrances = append(entrances, run[0], run[len(run)-1])
+		}
+		run = run[:0]
+	}
+
+	length := hi.Z - lo.Z
+	if !horizontal {
+		length = hi.X - lo.X
+	}
+	for i := 0; i <= length; i++ {
+		var inside, outside Node
+		if horizontal {
+			inside = getGroundNode(h.grid, hi.X, lo.Z+i)
+			outside = getGroundNode(h.grid, hi.X+1, lo.Z+i)
+		} else {
+			inside = getGroundNode(h.grid, lo.X+i, hi.Z)
+			outside = getGroundNode(h.grid, lo.X+i, hi.Z+1)
+		}
+		if canStand(h.grid, inside.cell, h.opts) && canStand(h.grid, outside.cell, h.opts) {
+			run = append(run, entrance{inside.cell, outside.cell})
+		} else {
+			flush()
+		}
+	}
+	flush()
+	h.borders[[2]int{a, b}] = entrances
+}
+
+// entrancesOf returns the entrance cells lying inside cluster in a stable order.
+func (h *hierarchy) entrancesOf(cluster int) []Cell {
+	seen := make(map[Cell]bool)
+	var cells []Cell
+	add := func(cell Cell) {
+		if !seen[cell] && h.clusterIndex(cell) == cluster {
+			seen[cell] = true
+			cells = append(cells, cell)
+		}
+	}
+	cx, cz := cluster%h.clusters.X, cluster/h.clusters.X
+	neighbours := [][2]int{{cluster, cluster + 1}, {cluster - 1, cluster}, {cluster, cluster + h.clusters.X}, {cluster - h.clusters.X, cluster}}
+	valid := []bool{cx+1 < h.clusters.X, cx > 0, cz+1 < h.clusters.Z, cz > 0}
+	for i, key := range neighbours {
+		if !valid[i] {
+			continue
+		}
+		for _, e := range h.borders[key] {
+			add(e.inside)
+			add(e.outside)
+		}
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Z != cells[j].Z {
+			return cells[i].Z < cells[j].Z
+		}
+		return cells[i].X < cells[j].X
+	})
+	return cells
+}
+
+// buildIntraEdges connects every pair of entrances of cluster that can reach
+// each other without leaving it.
+func (h *hierarchy) buildIntraEdges(cluster int) {
+	cells := h.entrancesOf(cluster)
+	var edges []abstractEdge
+	for _, from := range cells {
+		costs, _ := h.clusterCosts(cluster, from, cells, false)
+		for i, to := range cells {
+			if to != from && !math.IsInf(costs[i], 1) {
+				edges = append(edges, abstractEdge{from, to, costs[i]})
+			}
+		}
+	}
+	h.intra[cluster] = edges
+}
+
+// searchCluster runs a search from `from` that never leaves cluster. With a
+// goal it is an A* that stops on reaching it, otherwise a Dijkstra that
+// stops once every target is settled. With inbound the steps are charged
+// toward `from`, as walked by an agent heading there. The caller releases
+// the returned state.
+func (h *hierarchy) searchCluster(cluster int, from Cell, goal *Node, targets []Cell, inbound bool) (*searchState, int) {
+	lo, hi := h.clusterBounds(cluster)
+	search := acquireSearch()
+	start := getGroundNode(h.grid, from.X, from.Z)
+	opts := h.opts
+	if goal == nil {
+		opts.Heuristic = zeroHeuristic{}
+		goal = &start
+	} else {
+		targets = []Cell{goal.cell}
+	}
+	first := search.add(start.cell, start.position)
+	first.hCost = estimate(first.position, goal.position, opts)
+	heap.Push(&search.openSet, first)
+
+	remaining := make(map[Cell]bool, len(targets))
+	for _, target := range targets {
+		remaining[target] = true
+	}
+	expanded := 0
+	for search.openSet.Len() > 0 {
+		current := getCurrentNode(&search.openSet)
+		current.closed = true
+		expanded++
+
+		delete(remaining, current.cell)
+		if len(remaining) == 0 {
+			break
+		}
+
+		search.neighbors = getGroundNeighbors(h.grid, current, h.opts, search.neighbors[:0])
+		for i := range search.neighbors {
+			neighbor := &search.neighbors[i]
+			if neighbor.cell.X < lo.X || neighbor.cell.X > hi.X || neighbor.cell.Z < lo.Z || neighbor.cell.Z > hi.Z {
+				continue
+			}
+			if !inbound {
+				updateNeighbor(neighbor, current, goal, search, opts)
+				continue
+			}
+			node := search.get(neighbor.cell)
+			if node != nil && node.closed {
+				continue
+			}
+			if cost, ok := stepCost(neighbor, current, opts); ok {
+				relaxNode(search, node, neighbor, current, current.gCost+cost, goal, opts)
+			}
+		}
+	}
+	return search, expanded
+}
+
+// clusterCosts returns the cost from `from` to each of targets inside
+// cluster, or from each target to `from` with inbound, +Inf for targets it
+// cannot reach.
+func (h *hierarchy) clusterCosts(cluster int, from Cell, targets []Cell, inbound bool) ([]float64, int) {
+	search, expanded := h.searchCluster(cluster, from, nil, targets, inbound)
+	defer releaseSearch(search)
+	costs := make([]float64, len(targets))
+	for i, target := range targets {
+		costs[i] = math.Inf(1)
+		if node := search.get(target); node != nil && node.closed {
+			costs[i] = node.gCost
+		}
+	}
+	return costs, expanded
+}
+
+// clusterPath returns the cells from `from` to `to` without leaving cluster,
+// excluding `from` itself.
+func (h *hierarchy) clusterPath(cluster int, from, to Cell) ([]rl.Vector3, float64, int, bool) {
+	goal := getGroundNode(h.grid, to.X, to.Z)
+	search, expanded := h.searchCluster(cluster, from, &goal, nil, false)
+	defer releaseSearch(search)
+	node := search.get(to)
+	if node == nil || !node.closed {
+		return nil, 0, expanded, false
+	}
+	return reconstructPath(node)[1:], node.gCost, expanded, true
+}
+
+// FindPath searches the abstract graph for a route from start to target and
+// refines it into cells. Queries inside a single cluster are answered
+// directly, and queries the abstract graph cannot answer fall back to a
+// plain search when opts.AllowPartial is set. The grid is read locked while
+// the query runs.
+func (h *hierarchy) FindPath(start, target rl.Vector3) Result {
+	h.grid.RLock()
+	defer h.grid.RUnlock()
+	h.refresh()
+
+	startNode := getNodeFromWorldPos(h.grid, start, h.opts)
+	targetNode := getNodeFromWorldPos(h.grid, target, h.opts)
+	if !h.grid.InBounds(startNode.cell) || !h.grid.InBounds(targetNode.cell) {
+		return Result{Status: StatusUnreachable, Err: ErrOutOfBounds}
+	}
+	if !canStand(h.grid, targetNode.cell, h.opts) {
+		if !h.opts.AllowPartial {
+			return Result{Status: StatusUnreachable, Err: ErrBlocked}
+		}
+		// The closest reachable cell may lie in any cluster, let the grid
+		// search find it and report the goal as blocked
+		return findPath(context.Background(), h.grid, start, target, h.opts)
+	}
+
+	startCluster := h.clusterIndex(startNode.cell)
+	targetCluster := h.clusterIndex(targetNode.cell)
+	expanded := 0
+
+	// Temporarily link start and target to the entrances of their clusters
+	startCells := h.entrancesOf(startCluster)
+	startCosts, n := h.clusterCosts(startCluster, startNode.cell, startCells, false)
+	expanded += n
+	targetCells := h.entrancesOf(targetCluster)
+	targetCosts, n := h.clusterCosts(targetCluster, targetNode.cell, targetCells, true)
+	expanded += n
+	toTarget := make(map[Cell]float64)
+	for i, cell := range targetCells {
+		if !math.IsInf(targetCosts[i], 1) {
+			toTarget[cell] = targetCosts[i]
+		}
+	}
+	if startCluster == targetCluster {
+		// The route may stay inside the cluster or leave it and come back,
+		// so the direct link competes with the abstract graph
+		_, cost, n, ok := h.clusterPath(startCluster, startNode.cell, targetNode.cell)
+		expanded += n
+		if known, linked := toTarget[startNode.cell]; ok && (!linked || cost < known) {
+			toTarget[startNode.cell] = cost
+		}
+	}
+
+	route, n, ok := h.searchAbstract(startNode, targetNode, startCells, startCosts, toTarget)
+	expanded += n
+	if !ok {
+		if h.opts.AllowPartial {
+			return findPath(context.Background(), h.grid, start, target, h.opts)
+		}
+		return Result{Status: StatusUnreachable, Expanded: expanded, Err: ErrUnreachable}
+	}
+
+	// Refine each abstract step into cells
+	path := []rl.Vector3{startNode.position}
+	cost := 0.0
+	for i := 1; i < len(route); i++ {
+		from, to := route[i-1], route[i]
+		cluster := h.clusterIndex(from)
+		if cluster != h.clusterIndex(to) {
+			fromNode := getGroundNode(h.grid, from.X, from.Z)
+			toNode := getGroundNode(h.grid, to.X, to.Z)
+			step, _ := stepCost(&fromNode, &toNode, h.opts)
+			path = append(path, toNode.position)
+			cost += step
+			continue
+		}
+		segment, segmentCost, n, ok := h.clusterPath(cluster, from, to)
+		expanded += n
+		if !ok {
+			return Result{Status: StatusUnreachable, Expanded: expanded, Err: ErrUnreachable}
+		}
+		path = append(path, segment...)
+		cost += segmentCost
+	}
+	return Result{Status: StatusFound, Path: postProcess(h.grid, path, h.opts), Cost: cost, Expanded: expanded}
+}
+
+// searchAbstract runs A* over the entrance graph extended with the temporary
+// start and target links, returning the cells of the abstract route.
+func (h *hierarchy) searchAbstract(startNode, targetNode Node, startCells []Cell, startCosts []float64, toTarget map[Cell]float64) ([]Cell, int, bool) {
+	search := acquireSearch()
+	defer releaseSearch(search)
+
+	first := search.add(startNode.cell, startNode.position)
+	first.hCost = estimate(first.position, targetNode.position, h.opts)
+	heap.Push(&search.openSet, first)
+	expanded := 0
+
+	for search.openSet.Len() > 0 {
+		current := getCurrentNode(&search.openSet)
+		current.closed = true
+		expanded++
+
+		if current.cell == targetNode.cell {
+			var route []Cell
+			for node := current; node != nil; node = node.parent {
+				route = append(route, node.cell)
+			}
+			for i, j := 0, len(route)-1; i < j; i, j = i+1, j-1 {
+				route[i], route[j] = route[j], route[i]
+			}
+			return route, expanded, true
+		}
+		if h.opts.MaxNodes > 0 && expanded >= h.opts.MaxNodes {
+			return nil, expanded, false
+		}
+
+		edges := h.graph[current.cell]
+		toTargetCost, linksTarget := toTarget[current.cell]
+		if current == first || linksTarget {
+			// Copy before adding the temporary links so the graph is left untouched
+			edges = append([]abstractEdge(nil), edges...)
+		}
+		if current == first {
+			for i, cell := range startCells {
+				if !math.IsInf(startCosts[i], 1) {
+					edges = append(edges, abstractEdge{current.cell, cell, startCosts[i]})
+				}
+			}
+		}
+		if linksTarget {
+			edges = append(edges, abstractEdge{current.cell, targetNode.cell, toTargetCost})
+		}
+		for _, edge := range edges {
+			node := search.get(edge.to)
+			if node != nil && node.closed {
+				continue
+			}
+			neighbor := getGroundNode(h.grid, edge.to.X, edge.to.Z)
+			relaxNode(search, node, &neighbor, current, current.gCost+edge.cost, &targetNode, h.opts)
+		}
+	}
+	return nil, expanded, false
+}
+
+// zeroHeuristic turns A* into Dijkstra for searches without a single goal.
+type zeroHeuristic struct{}
+
+func (zeroHeuristic) Estimate(a, b rl.Vector3) float64 {
+	return 0
+}
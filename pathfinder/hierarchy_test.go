@@ -0,0 +1,50 @@
+package pathfinder
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestHierarchyClusterSize(t *testing.T) {
+	m, err := LoadTextMap("testdata/maze.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{-3, 0, 1} {
+		h := NewHierarchy(m.Grid, size, DefaultOptions())
+		if got := h.GetClusterSize(); got != 1 {
+			t.Errorf("cluster size %d gave clusters of %d cells, want 1", size, got)
+		}
+		if result := h.FindPath(m.Start, m.Goal); result.Status != StatusFound {
+			t.Errorf("cluster size %d: status %s, want found", size, result.Status)
+		}
+		h.Close()
+	}
+}
+
+func TestHierarchyUpdateRegion(t *testing.T) {
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 16, Y: 1, Z: 16})
+	h := NewHierarchy(grid, 4, DefaultOptions())
+	defer h.Close()
+	start, goal := rl.NewVector3(1, 0, 8), rl.NewVector3(14, 0, 8)
+	before := h.FindPath(start, goal)
+
+	// A wall across the grid with a single gap far from the straight line
+	for z := 0; z < 15; z++ {
+		grid.SetBlocked(Cell{X: 8, Z: z}, true)
+	}
+	after := h.FindPath(start, goal)
+	want := FindPath(grid, start, goal, DefaultOptions())
+	if after.Status != StatusFound || after.Cost <= before.Cost {
+		t.Fatalf("after the wall: %s at cost %.4f, before %.4f", after.Status, after.Cost, before.Cost)
+	}
+	if after.Cost < want.Cost-1e-9 {
+		t.Errorf("cost %.4f is below the optimal %.4f", after.Cost, want.Cost)
+	}
+	for _, pos := range after.Path {
+		if cell := grid.WorldToCell(pos); grid.IsBlocked(cell) {
+			t.Errorf("path crosses the blocked cell %v", cell)
+		}
+	}
+}
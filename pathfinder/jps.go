@@ -0,0 +1,254 @@
+package pathfinder
+
+import (
+	"container/heap"
+	"context"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// canJump reports whether Jump Point Search returns the same paths as A*
+// for opts. It needs 8-connected ground movement without corner cutting and
+// without per-step costs or a cost map. Height limits and climb costs are
+// handled by stopping the jumps wherever the terrain is not flat.
+func canJump(opts Options) bool {
+	return opts.Mode == ModeGround &&
+		opts.Connectivity == Connect8 &&
+		opts.Corners == CornerNever &&
+		opts.Cost == nil &&
+		opts.CostMap == nil
+}
+
+// jumper walks runs of ground cells for a single JPS query. It scans the
+// ground map of the grid, which follows every update of the grid, instead of
+// sampling the terrain and obstacles at each step.
+type jumper struct {
+	grid   NavGrid
+	target Cell
+	opts   Options
+
+	columns, rows int
+	ground        []groundColumn
+	// clearance is nil for agents without a radius
+	clearance []float32
+}
+
+func newJumper(grid NavGrid, target Cell, opts Options) *jumper {
+	size := grid.GetSize()
+	j := &jumper{grid: grid, target: target, opts: opts, columns: size.X, rows: size.Z, ground: grid.getGroundMap()}
+	if opts.AgentRadius > 0 {
+		j.clearance = grid.getClearanceMap()
+	}
+	return j
+}
+
+// walkable reports whether the ground cell in column x, z can be entered.
+func (j *jumper) walkable(x, z int) bool {
+	if x < 0 || z < 0 || x >= j.columns || z >= j.rows {
+		return false
+	}
+	i := z*j.columns + x
+	return j.ground[i].walkable && (j.clearance == nil || j.clearance[i] >= j.opts.AgentRadius)
+}
+
+// flat reports whether the columns around x, z lie at the height of x, z.
+// Steps there all cost their length, which the pruning rules of JPS rely on;
+// elsewhere every neighbour counts as forced. Column x, z lies on the grid.
+func (j *jumper) flat(x, z int) bool {
+	return j.ground[z*j.columns+x].flat
+}
+
+// node returns the ground node of column x, z on the grid.
+func (j *jumper) node(x, z int) Node {
+	column := j.ground[z*j.columns+x]
+	position := j.grid.CellToWorld(Cell{X: x, Z: z})
+	position.Y = column.height
+	return Node{cell: Cell{x, column.y, z}, position: position}
+}
+
+// jump moves from node in direction dx, dz until it finds a jump point: the
+// target, a cell with a forced neighbour or a cell off flat terrain. It
+// returns the jump point and the cost of reaching it from node.
+func (j *jumper) jump(node Node, dx, dz int) (Node, float64, bool) {
+	x, z := node.cell.X, node.cell.Z
+	previous := node
+	cost := 0.0
+	for {
+		if dx != 0 && dz != 0 && !(j.walkable(x+dx, z) && j.walkable(x, z+dz)) {
+			return Node{}, 0, false
+		}
+		x, z = x+dx, z+dz
+		if !j.walkable(x, z) {
+			return Node{}, 0, false
+		}
+		next := j.node(x, z)
+		step, ok := stepCost(&previous, &next, j.opts)
+		if !ok {
+			return Node{}, 0, false
+		}
+		cost += step
+		previous = next
+
+		if x == j.target.X && z == j.target.Z || !j.flat(x, z) {
+			return next, cost, true
+		}
+		if dx != 0 && dz != 0 {
+			// A diagonal run stops where either straight run finds something
+			if j.scan(x, z, dx, 0) || j.scan(x, z, 0, dz) {
+				return next, cost, true
+			}
+		} else if j.forced(x, z, dx, dz) {
+			return next, cost, true
+		}
+	}
+}
+
+// scan reports whether a straight jump from the flat column x, z in
+// direction dx, dz finds a jump point. It only reads the ground map: every
+// step of the run is level, so it is allowed and costs its length.
+func (j *jumper) scan(x, z, dx, dz int) bool {
+	for {
+		x, z = x+dx, z+dz
+		if !j.walkable(x, z) {
+			return false
+		}
+		if x == j.target.X && z == j.target.Z || !j.flat(x, z) || j.forced(x, z, dx, dz) {
+			return true
+		}
+	}
+}
+
+// forced reports whether column x, z, entered straight in direction dx, dz,
+// has a neighbour only reachable through it.
+func (j *jumper) forced(x, z, dx, dz int) bool {
+	if dx != 0 {
+		return (j.walkable(x, z-1) && !j.walkable(x-dx, z-1)) || (j.walkable(x, z+1) && !j.walkable(x-dx, z+1))
+	}
+	return (j.walkable(x-1, z) && !j.walkable(x-1, z-dz)) || (j.walkable(x+1, z) && !j.walkable(x+1, z-dz))
+}
+
+// directions returns the pruned set of directions to jump in from node,
+// based on the direction it was entered from.
+func (j *jumper) directions(node *Node, directions [][2]int) [][2]int {
+	x, z := node.cell.X, node.cell.Z
+	if node.parent == nil || !j.flat(x, z) {
+		for dx := -1; dx <= 1; dx++ {
+			for dz := -1; dz <= 1; dz++ {
+				if dx != 0 || dz != 0 {
+					directions = append(directions, [2]int{dx, dz})
+				}
+			}
+		}
+		return directions
+	}
+
+	dx := sign(x - node.parent.cell.X)
+	dz := sign(z - node.parent.cell.Z)
+	switch {
+	case dx != 0 && dz != 0:
+		directions = append(directions, [2]int{0, dz}, [2]int{dx, 0}, [2]int{dx, dz})
+	case dx != 0:
+		directions = append(directions, [2]int{dx, 0})
+		if j.walkable(x, z+1) {
+			directions = append(directions, [2]int{0, 1}, [2]int{dx, 1})
+		}
+		if j.walkable(x, z-1) {
+			directions = append(directions, [2]int{0, -1}, [2]int{dx, -1})
+		}
+	default:
+		directions = append(directions, [2]int{0, dz})
+		if j.walkable(x+1, z) {
+			directions = append(directions, [2]int{1, 0}, [2]int{1, dz})
+		}
+		if j.walkable(x-1, z) {
+			directions = append(directions, [2]int{-1, 0}, [2]int{-1, dz})
+		}
+	}
+	return directions
+}
+
+// findPathJPS runs Jump Point Search between two validated ground nodes.
+// Only jump points enter the open set; the returned path is filled back in
+// cell by cell so it has the same shape as an A* path.
+func findPathJPS(ctx context.Context, grid NavGrid, startNode, targetNode Node, failure error, opts Options) Result {
+	j := newJumper(grid, targetNode.cell, opts)
+	fill := func(node *Node) []rl.Vector3 {
+		return fillJumpPath(grid, reconstructPath(node))
+	}
+
+	search := acquireSearch()
+	defer releaseSearch(search)
+	search.onGround(grid)
+
+	first := search.add(startNode.cell, startNode.position)
+	first.hCost = estimate(first.position, targetNode.position, opts)
+	heap.Push(&search.openSet, first)
+	closest := first
+	expanded := 0
+	var directions [][2]int
+
+	for search.openSet.Len() > 0 {
+		current := getCurrentNode(&search.openSet)
+
+		current.closed = true
+		expanded++
+		opts.recording.expand(current)
+
+		if current.cell == targetNode.cell {
+			return Result{Status: StatusFound, Path: postProcess(grid, fill(current), opts), Cost: current.gCost, Expanded: expanded}
+		}
+		if expanded%cancelCheckInterval == 0 && ctx.Err() != nil {
+			return Result{Status: StatusCanceled, Expanded: expanded, Err: ctx.Err()}
+		}
+		if current.hCost < closest.hCost {
+			closest = current
+		}
+		if opts.MaxNodes > 0 && expanded >= opts.MaxNodes {
+			return failedResult(grid, closest, expanded, ErrNodeLimit, opts, fill)
+		}
+
+		directions = j.directions(current, directions[:0])
+		for _, direction := range directions {
+			jumpPoint, cost, ok := j.jump(*current, direction[0], direction[1])
+			if !ok {
+				continue
+			}
+			node := search.get(jumpPoint.cell)
+			if node != nil && node.closed {
+				continue
+			}
+			relaxNode(search, node, &jumpPoint, current, current.gCost+cost, &targetNode, opts)
+		}
+	}
+
+	return failedResult(grid, closest, expanded, failure, opts, fill)
+}
+
+// fillJumpPath inserts the ground cells lying between consecutive jump points.
+func fillJumpPath(grid NavGrid, jumps []rl.Vector3) []rl.Vector3 {
+	if len(jumps) < 2 {
+		return jumps
+	}
+	path := []rl.Vector3{jumps[0]}
+	for i := 1; i < len(jumps); i++ {
+		from := grid.WorldToCell(jumps[i-1])
+		to := grid.WorldToCell(jumps[i])
+		dx, dz := sign(to.X-from.X), sign(to.Z-from.Z)
+		for x, z := from.X+dx, from.Z+dz; x != to.X || z != to.Z; x, z = x+dx, z+dz {
+			path = append(path, getGroundNode(grid, x, z).position)
+		}
+		path = append(path, jumps[i])
+	}
+	return path
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
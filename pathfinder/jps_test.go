@@ -0,0 +1,172 @@
+package pathfinder
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// jpsCase is a map both searches solve.
+type jpsCase struct {
+	name        string
+	grid        NavGrid
+	start, goal rl.Vector3
+	want        Status
+}
+
+// jpsCases returns the fixture maps without cost maps, which JPS answers
+// itself, and a terraced terrain crossed by a cliff with a single ramp.
+func jpsCases(t *testing.T) []jpsCase {
+	var cases []jpsCase
+	for _, fixture := range []struct {
+		name string
+		want Status
+	}{
+		{"corners", StatusUnreachable},
+		{"maze", StatusFound},
+		{"open", StatusFound},
+		{"unreachable", StatusUnreachable},
+		{"wall", StatusFound},
+	} {
+		name := fixture.name
+		m, err := LoadTextMap("testdata/" + name + ".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Costs != nil {
+			t.Fatalf("%s: has a cost map, JPS would fall back to A*", name)
+		}
+		cases = append(cases, jpsCase{name, m.Grid, m.Start, m.Goal, fixture.want})
+	}
+	return append(cases, jpsCase{"terraces", newTerraceGrid(), rl.NewVector3(2, 0, 2), rl.NewVector3(29, 0, 29), StatusFound})
+}
+
+// newTerraceGrid returns 32 by 32 columns rising in low terraces, with a
+// cliff along x = 16 that only a ramp in rows 12 to 19 climbs, and a few
+// boulders.
+func newTerraceGrid() NavGrid {
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 32, Y: 4, Z: 32})
+	grid.SetHeightFunc(func(x, z float32) float32 {
+		height := 0.3 * float32(math.Floor(float64(z)/8))
+		switch {
+		case z >= 12 && z < 20:
+			return height + float32(math.Min(math.Max(float64(x-12)*0.25, 0), 1))
+		case x >= 16:
+			return height + 1
+		}
+		return height
+	})
+	for _, box := range []rl.BoundingBox{
+		rl.NewBoundingBox(rl.NewVector3(4, 0, 3), rl.NewVector3(6, 4, 9)),
+		rl.NewBoundingBox(rl.NewVector3(9, 0, 20), rl.NewVector3(14, 4, 21)),
+		rl.NewBoundingBox(rl.NewVector3(20, 0, 24), rl.NewVector3(21, 4, 31)),
+	} {
+		grid.AddObstacle(box)
+	}
+	return grid
+}
+
+func jpsOptions() Options {
+	opts := DefaultOptions()
+	opts.StringPull = false
+	opts.MaxNodes = 0
+	opts.AllowPartial = false
+	return opts
+}
+
+func TestJPSMatchesAStar(t *testing.T) {
+	for _, c := range jpsCases(t) {
+		t.Run(c.name, func(t *testing.T) {
+			opts := jpsOptions()
+			astar := FindPath(c.grid, c.start, c.goal, opts)
+			opts.Algorithm = AlgorithmJPS
+			jps := FindPath(c.grid, c.start, c.goal, opts)
+
+			if astar.Status != c.want || jps.Status != c.want {
+				t.Fatalf("JPS status %s, A* status %s, want %s", jps.Status, astar.Status, c.want)
+			}
+			if astar.Status != StatusFound {
+				return
+			}
+			if math.Abs(jps.Cost-astar.Cost) > 1e-9 {
+				t.Errorf("JPS cost %.6f, A* cost %.6f", jps.Cost, astar.Cost)
+			}
+			if jps.Expanded >= astar.Expanded {
+				t.Errorf("JPS expanded %d nodes, A* only %d", jps.Expanded, astar.Expanded)
+			}
+			last := jps.Path[len(jps.Path)-1]
+			if c.grid.WorldToCell(last) != c.grid.WorldToCell(astar.Path[len(astar.Path)-1]) {
+				t.Errorf("JPS path ends at %v, A* path at %v", last, astar.Path[len(astar.Path)-1])
+			}
+		})
+	}
+}
+
+func TestJPSFallback(t *testing.T) {
+	m, err := LoadTextMap("testdata/maze.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name      string
+		configure func(opts *Options)
+		jumps     bool
+	}{
+		{"default", func(opts *Options) {}, true},
+		{"height limits", func(opts *Options) { opts.MaxStepHeight, opts.MaxSlope, opts.ClimbCost = 1, 45, 2 }, true},
+		{"agent radius", func(opts *Options) { opts.AgentRadius = 0.4 }, true},
+		{"3D mode", func(opts *Options) { opts.Mode = Mode3D }, false},
+		{"4-connected", func(opts *Options) { opts.Connectivity = Connect4 }, false},
+		{"one free corner", func(opts *Options) { opts.Corners = CornerOneFree }, false},
+		{"cut corners", func(opts *Options) { opts.Corners = CornerAlways }, false},
+		{"cost func", func(opts *Options) {
+			opts.Cost = func(from, to Cell, distance float64) float64 { return distance }
+		}, false},
+		{"cost map", func(opts *Options) { opts.CostMap = NewCostMap(12, 7, 1) }, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			opts := jpsOptions()
+			c.configure(&opts)
+			if got := canJump(opts); got != c.jumps {
+				t.Fatalf("canJump = %v, want %v", got, c.jumps)
+			}
+
+			astar := FindPath(m.Grid, m.Start, m.Goal, opts)
+			opts.Algorithm = AlgorithmJPS
+			jps := FindPath(m.Grid, m.Start, m.Goal, opts)
+			if math.Abs(jps.Cost-astar.Cost) > 1e-9 || jps.Status != astar.Status {
+				t.Fatalf("JPS %s at cost %.6f, A* %s at cost %.6f", jps.Status, jps.Cost, astar.Status, astar.Cost)
+			}
+			// Falling back runs the very same A* search
+			if fellBack := jps.Expanded == astar.Expanded; fellBack == c.jumps {
+				t.Errorf("JPS expanded %d nodes and A* %d, want jumping %v", jps.Expanded, astar.Expanded, c.jumps)
+			}
+		})
+	}
+}
+
+// TestJPSFaster times both searches across the walled benchmark grid,
+// keeping the fastest of a few runs of each.
+func TestJPSFaster(t *testing.T) {
+	if testing.Short() {
+		t.Skip("times searches")
+	}
+	grid := newBenchGrid(benchGridSize, true)
+	goal := rl.NewVector3(benchGridSize-1, 0, benchGridSize-1)
+	timing := func(algorithm Algorithm) time.Duration {
+		opts := jpsOptions()
+		opts.Algorithm = algorithm
+		fastest := time.Duration(math.MaxInt64)
+		for i := 0; i < 5; i++ {
+			start := time.Now()
+			FindPath(grid, rl.Vector3{}, goal, opts)
+			fastest = min(fastest, time.Since(start))
+		}
+		return fastest
+	}
+	if astar, jps := timing(AlgorithmAStar), timing(AlgorithmJPS); jps >= astar {
+		t.Errorf("JPS took %s, A* %s", jps, astar)
+	}
+}
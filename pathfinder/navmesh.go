@@ -0,0 +1,617 @@
+package pathfinder
+
+import (
+	"container/heap"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// navMeshVersion is written to every saved navigation mesh so files from an
+// incompatible layout are rejected on load.
+const navMeshVersion = 2
+
+// navMeshKeySamples is the number of terrain samples along each side of the
+// bounds hashed into a navigation mesh key.
+const navMeshKeySamples = 32
+
+// NavMeshConfig describes the area a navigation mesh covers and the agent
+// that walks it.
+type NavMeshConfig struct {
+	// Min and Max bound the walkable ground on X and Z; Min.Y is the ground
+	// height when Height is nil
+	Min, Max rl.Vector3
+	// AgentRadius keeps the mesh this far away from obstacles and the bounds
+	AgentRadius float32
+	// AgentHeight lets the agent walk under obstacles starting above it, 0
+	// treats every obstacle as blocking
+	AgentHeight float32
+	// Height samples the terrain at each mesh vertex
+	Height HeightFunc
+}
+
+// Polygon is a convex navigation mesh polygon. Its vertices wind
+// counter-clockwise on the X/Z plane and Neighbors[i] is the polygon across
+// the edge from Vertices[i] to the next vertex, or -1 on the mesh border.
+type Polygon struct {
+	Vertices  []int
+	Neighbors []int
+}
+
+// NavMesh answers path queries over convex polygons covering the walkable
+// ground. Paths follow the shortest line through the polygon corridor found
+// by A*, so they only turn at obstacle corners.
+type NavMesh interface {
+	GetVertices() []rl.Vector3
+	GetPolygons() []Polygon
+	FindPolygon(pos rl.Vector3) (int, bool)
+	FindPath(start, target rl.Vector3) Result
+	GetKey() string
+	Save(path string) error
+}
+
+type navMesh struct {
+	Version int `json:"version"`
+	// Key identifies the config and obstacles the mesh was built from
+	Key      string       `json:"key"`
+	Vertices []rl.Vector3 `json:"vertices"`
+	Polygons []Polygon    `json:"polygons"`
+}
+
+// BuildNavMesh creates a new instance of NavMesh covering config's bounds
+// with the obstacles cut out, grown by the agent radius.
+func BuildNavMesh(config NavMeshConfig, obstacles ...rl.BoundingBox) NavMesh {
+	groundAt := func(x, z float32) float32 {
+		if config.Height == nil {
+			return config.Min.Y
+		}
+		return config.Height(x, z)
+	}
+
+	r := config.AgentRadius
+	minX, minZ := config.Min.X+r, config.Min.Z+r
+	maxX, maxZ := config.Max.X-r, config.Max.Z-r
+	mesh := &navMesh{Version: navMeshVersion, Key: NavMeshKey(config, obstacles...)}
+	if minX >= maxX || minZ >= maxZ {
+		return mesh
+	}
+
+	// Split the bounds along every obstacle edge so each piece is either
+	// fully blocked or fully free
+	xs := []float32{minX, maxX}
+	zs := []float32{minZ, maxZ}
+	var blockers []rl.BoundingBox
+	for _, box := range obstacles {
+		ground := groundAt((box.Min.X+box.Max.X)/2, (box.Min.Z+box.Max.Z)/2)
+		if box.Max.Y <= ground || (config.AgentHeight > 0 && box.Min.Y >= ground+config.AgentHeight) {
+			continue
+		}
+		grown := rl.NewBoundingBox(
+			rl.NewVector3(max(box.Min.X-r, minX), box.Min.Y, max(box.Min.Z-r, minZ)),
+			rl.NewVector3(min(box.Max.X+r, maxX), box.Max.Y, min(box.Max.Z+r, maxZ)),
+		)
+		if grown.Min.X >= grown.Max.X || grown.Min.Z >= grown.Max.Z {
+			continue
+		}
+		blockers = append(blockers, grown)
+		xs = append(xs, grown.Min.X, grown.Max.X)
+		zs = append(zs, grown.Min.Z, grown.Max.Z)
+	}
+	slices.Sort(xs)
+	slices.Sort(zs)
+	xs = slices.Compact(xs)
+	zs = slices.Compact(zs)
+
+	columns, rows := len(xs)-1, len(zs)-1
+	used := make([]bool, columns*rows)
+	for i := range used {
+		x := (xs[i%columns] + xs[i%columns+1]) / 2
+		z := (zs[i/columns] + zs[i/columns+1]) / 2
+		for _, box := range blockers {
+			if x > box.Min.X && x < box.Max.X && z > box.Min.Z && z < box.Max.Z {
+				used[i] = true
+				break
+			}
+		}
+	}
+
+	// Merge free pieces greedily into the largest rectangles: first along X,
+	// then along Z while the whole run below is free
+	var rects [][4]int
+	for row := 0; row < rows; row++ {
+		for column := 0; column < columns; column++ {
+			if used[row*columns+column] {
+				continue
+			}
+			right := column + 1
+			for right < columns && !used[row*columns+right] {
+				right++
+			}
+			top := row + 1
+			for ; top < rows; top++ {
+				free := true
+				for c := column; c < right && free; c++ {
+					free = !used[top*columns+c]
+				}
+				if !free {
+					break
+				}
+			}
+			for z := row; z < top; z++ {
+				for c := column; c < right; c++ {
+					used[z*columns+c] = true
+				}
+			}
+			rects = append(rects, [4]int{column, row, right, top})
+		}
+	}
+
+	vertexIndex := make(map[[2]int]int)
+	vertexAt := func(column, row int) int {
+		key := [2]int{column, row}
+		if index, ok := vertexIndex[key]; ok {
+			return index
+		}
+		x, z := xs[column], zs[row]
+		mesh.Vertices = append(mesh.Vertices, rl.NewVector3(x, groundAt(x, z), z))
+		vertexIndex[key] = len(mesh.Vertices) - 1
+		return vertexIndex[key]
+	}
+	for _, rect := range rects {
+		vertexAt(rect[0], rect[1])
+		vertexAt(rect[2], rect[1])
+		vertexAt(rect[2], rect[3])
+		vertexAt(rect[0], rect[3])
+	}
+
+	// Walk each rectangle border through every vertex lying on it so
+	// neighbouring polygons always share whole edges
+	for _, rect := range rects {
+		var polygon Polygon
+		addVertex := func(column, row int) {
+			if index, ok := vertexIndex[[2]int{column, row}]; ok {
+				polygon.Vertices = append(polygon.Vertices, index)
+			}
+		}
+		for c := rect[0]; c < rect[2]; c++ {
+			addVertex(c, rect[1])
+		}
+		for z := rect[1]; z < rect[3]; z++ {
+			addVertex(rect[2], z)
+		}
+		for c := rect[2]; c > rect[0]; c-- {
+			addVertex(c, rect[3])
+		}
+		for z := rect[3]; z > rect[1]; z-- {
+			addVertex(rect[0], z)
+		}
+		mesh.Polygons = append(mesh.Polygons, polygon)
+	}
+	mesh.link()
+	return mesh
+}
+
+// NavMeshKey returns the key BuildNavMesh gives the mesh built from config
+// and obstacles. The terrain cannot be hashed itself, so it is sampled on a
+// grid across the bounds.
+func NavMeshKey(config NavMeshConfig, obstacles ...rl.BoundingBox) string {
+	hash := sha256.New()
+	write := func(values ...float32) {
+		binary.Write(hash, binary.LittleEndian, values)
+	}
+	binary.Write(hash, binary.LittleEndian, int32(navMeshVersion))
+	write(config.Min.X, config.Min.Y, config.Min.Z, config.Max.X, config.Max.Y, config.Max.Z)
+	write(config.AgentRadius, config.AgentHeight)
+	for _, box := range obstacles {
+		write(box.Min.X, box.Min.Y, box.Min.Z, box.Max.X, box.Max.Y, box.Max.Z)
+	}
+	if config.Height != nil {
+		for i := 0; i <= navMeshKeySamples; i++ {
+			for j := 0; j <= navMeshKeySamples; j++ {
+				x := config.Min.X + (config.Max.X-config.Min.X)*float32(i)/navMeshKeySamples
+				z := config.Min.Z + (config.Max.Z-config.Min.Z)*float32(j)/navMeshKeySamples
+				write(config.Height(x, z))
+			}
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// LoadOrBuildNavMesh loads the mesh baked to path when it was built from
+// config and obstacles. Otherwise the mesh is built and baked to path for
+// the next run, creating its folder; the error then tells why it could not
+// be saved, and the mesh is returned all the same.
+func LoadOrBuildNavMesh(path string, config NavMeshConfig, obstacles ...rl.BoundingBox) (NavMesh, error) {
+	key := NavMeshKey(config, obstacles...)
+	if mesh, err := LoadNavMesh(path); err == nil && mesh.GetKey() == key {
+		return mesh, nil
+	}
+	mesh := BuildNavMesh(config, obstacles...)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return mesh, err
+	}
+	return mesh, mesh.Save(path)
+}
+
+// LoadNavMesh reads a navigation mesh written by NavMesh.Save.
+func LoadNavMesh(path string) (NavMesh, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	mesh := &navMesh{}
+	if err := json.Unmarshal(data, mesh); err != nil {
+		return nil, fmt.Errorf("pathfinder: decode navmesh %s: %w", path, err)
+	}
+	if mesh.Version != navMeshVersion {
+		return nil, fmt.Errorf("pathfinder: navmesh %s has version %d, want %d", path, mesh.Version, navMeshVersion)
+	}
+	for _, polygon := range mesh.Polygons {
+		for _, v := range polygon.Vertices {
+			if v < 0 || v >= len(mesh.Vertices) {
+				return nil, fmt.Errorf("pathfinder: navmesh %s has vertex index %d out of range", path, v)
+			}
+		}
+	}
+	mesh.link()
+	return mesh, nil
+}
+
+// link fills in the neighbours of every polygon from their shared edges.
+func (m *navMesh) link() {
+	edges := make(map[[2]int]int)
+	for p, polygon := range m.Polygons {
+		for i, v := range polygon.Vertices {
+			edges[[2]int{v, polygon.Vertices[(i+1)%len(polygon.Vertices)]}] = p
+		}
+	}
+	for p := range m.Polygons {
+		polygon := &m.Polygons[p]
+		polygon.Neighbors = make([]int, len(polygon.Vertices))
+		for i, v := range polygon.Vertices {
+			neighbor, ok := edges[[2]int{polygon.Vertices[(i+1)%len(polygon.Vertices)], v}]
+			if !ok {
+				neighbor = -1
+			}
+			polygon.Neighbors[i] = neighbor
+		}
+	}
+}
+
+// Getters and Setters for navMesh
+func (m *navMesh) GetVertices() []rl.Vector3 {
+	return m.Vertices
+}
+
+func (m *navMesh) GetPolygons() []Polygon {
+	return m.Polygons
+}
+
+func (m *navMesh) GetKey() string {
+	return m.Key
+}
+
+// Save writes the mesh to path so it can be loaded instead of rebuilt.
+func (m *navMesh) Save(path string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// FindPolygon returns the polygon below pos on the X/Z plane.
+func (m *navMesh) FindPolygon(pos rl.Vector3) (int, bool) {
+	for p := range m.Polygons {
+		if m.contains(p, pos) {
+			return p, true
+		}
+	}
+	return -1, false
+}
+
+func (m *navMesh) contains(p int, pos rl.Vector3) bool {
+	vertices := m.Polygons[p].Vertices
+	for i, v := range vertices {
+		a, b := m.Vertices[v], m.Vertices[vertices[(i+1)%len(vertices)]]
+		if cross2D(a, b, pos) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// closestPoint returns the point of the mesh closest to pos on the X/Z
+// plane and the polygon it lies in.
+func (m *navMesh) closestPoint(pos rl.Vector3) (rl.Vector3, int) {
+	best, bestPolygon := pos, -1
+	bestDistance := float32(math.MaxFloat32)
+	for p := range m.Polygons {
+		point := m.closestInPolygon(p, pos)
+		if distance := distance2D(point, pos); distance < bestDistance {
+			best, bestPolygon, bestDistance = point, p, distance
+		}
+	}
+	return best, bestPolygon
+}
+
+// closestInPolygon returns the point of polygon p closest to pos on the X/Z plane.
+func (m *navMesh) closestInPolygon(p int, pos rl.Vector3) rl.Vector3 {
+	if m.contains(p, pos) {
+		return pos
+	}
+	vertices := m.Polygons[p].Vertices
+	best := pos
+	bestDistance := float32(math.MaxFloat32)
+	for i, v := range vertices {
+		a, b := m.Vertices[v], m.Vertices[vertices[(i+1)%len(vertices)]]
+		point := closestOnSegment2D(a, b, pos)
+		if distance := distance2D(point, pos); distance < bestDistance {
+			best, bestDistance = point, distance
+		}
+	}
+	return best
+}
+
+// heightAt interpolates the height of polygon p at pos from its vertices.
+func (m *navMesh) heightAt(p int, pos rl.Vector3) float32 {
+	vertices := m.Polygons[p].Vertices
+	a := m.Vertices[vertices[0]]
+	for i := 1; i+1 < len(vertices); i++ {
+		b, c := m.Vertices[vertices[i]], m.Vertices[vertices[i+1]]
+		area := cross2D(a, b, c)
+		if area == 0 {
+			continue
+		}
+		u := cross2D(b, c, pos) / area
+		v := cross2D(c, a, pos) / area
+		w := 1 - u - v
+		if u >= -1e-4 && v >= -1e-4 && w >= -1e-4 {
+			return u*a.Y + v*b.Y + w*c.Y
+		}
+	}
+	return a.Y
+}
+
+// meshNode is the search state of a single polygon.
+type meshNode struct {
+	polygon int
+	// entry is where the path enters the polygon
+	entry rl.Vector3
+	// portal is the edge of the parent polygon the path crosses to get here
+	portal int
+	gCost  float64
+	hCost  float64
+	parent *meshNode
+	index  int
+	closed bool
+}
+
+type meshQueue []*meshNode
+
+func (q meshQueue) Len() int { return len(q) }
+func (q meshQueue) Less(i, j int) bool {
+	return q[i].gCost+q[i].hCost < q[j].gCost+q[j].hCost
+}
+func (q meshQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+func (q *meshQueue) Push(x any) {
+	node := x.(*meshNode)
+	node.index = len(*q)
+	*q = append(*q, node)
+}
+func (q *meshQueue) Pop() any {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+	node.index = -1
+	return node
+}
+
+// FindPath searches the polygon corridor from start to target with A*
+// through the shortest crossing point of each edge, then pulls the path
+// tight with the funnel algorithm. A target off the mesh is moved to the
+// closest point on it. The mesh is never modified, so any goroutine may
+// call it.
+func (m *navMesh) FindPath(start, target rl.Vector3) Result {
+	startPolygon, ok := m.FindPolygon(start)
+	if !ok {
+		return Result{Status: StatusUnreachable, Err: ErrOutOfBounds}
+	}
+	var goalErr error
+	targetPolygon, ok := m.FindPolygon(target)
+	if !ok {
+		target, targetPolygon = m.closestPoint(target)
+		goalErr = ErrBlocked
+	}
+	start.Y = m.heightAt(startPolygon, start)
+	target.Y = m.heightAt(targetPolygon, target)
+
+	nodes := make(map[int]*meshNode)
+	first := &meshNode{polygon: startPolygon, entry: start, portal: -1, hCost: float64(rl.Vector3Distance(start, target)), index: -1}
+	nodes[startPolygon] = first
+	open := meshQueue{}
+	heap.Push(&open, first)
+	closest := first
+	expanded := 0
+
+	for open.Len() > 0 {
+		current := heap.Pop(&open).(*meshNode)
+		current.closed = true
+		expanded++
+
+		if current.polygon == targetPolygon {
+			return m.corridorResult(current, start, target, expanded, goalErr)
+		}
+		if current.hCost < closest.hCost {
+			closest = current
+		}
+
+		polygon := m.Polygons[current.polygon]
+		for i, neighbor := range polygon.Neighbors {
+			if neighbor < 0 {
+				continue
+			}
+			node := nodes[neighbor]
+			if node != nil && node.closed {
+				continue
+			}
+			a, b := m.Vertices[polygon.Vertices[i]], m.Vertices[polygon.Vertices[(i+1)%len(polygon.Vertices)]]
+			entry := portalPoint(a, b, current.entry, target)
+			gCost := current.gCost + float64(rl.Vector3Distance(current.entry, entry))
+			if node == nil {
+				node = &meshNode{polygon: neighbor, hCost: float64(rl.Vector3Distance(entry, target)), index: -1}
+				nodes[neighbor] = node
+			} else if gCost >= node.gCost {
+				continue
+			}
+			node.entry = entry
+			node.portal = i
+			node.gCost = gCost
+			node.parent = current
+			if node.index < 0 {
+				heap.Push(&open, node)
+			} else {
+				heap.Fix(&open, node.index)
+			}
+		}
+	}
+
+	if closest == first {
+		return Result{Status: StatusUnreachable, Expanded: expanded, Err: ErrUnreachable}
+	}
+	end := m.closestInPolygon(closest.polygon, target)
+	end.Y = m.heightAt(closest.polygon, end)
+	return m.corridorResult(closest, start, end, expanded, ErrUnreachable)
+}
+
+// corridorResult turns the polygon corridor ending at node into a path from
+// start to end. A non-nil err marks the result as partial.
+func (m *navMesh) corridorResult(node *meshNode, start, end rl.Vector3, expanded int, err error) Result {
+	portals := [][2]rl.Vector3{{end, end}}
+	for ; node.parent != nil; node = node.parent {
+		vertices := m.Polygons[node.parent.polygon].Vertices
+		a, b := m.Vertices[vertices[node.portal]], m.Vertices[vertices[(node.portal+1)%len(vertices)]]
+		// Leaving a counter-clockwise polygon, the edge end is on the left
+		portals = append(portals, [2]rl.Vector3{b, a})
+	}
+	portals = append(portals, [2]rl.Vector3{start, start})
+	slices.Reverse(portals)
+
+	path := funnel(portals)
+	cost := 0.0
+	for i := 1; i < len(path); i++ {
+		cost += float64(rl.Vector3Distance(path[i-1], path[i]))
+	}
+	status := StatusFound
+	if err != nil {
+		status = StatusPartial
+	}
+	return Result{Status: status, Path: path, Cost: cost, Expanded: expanded, Err: err}
+}
+
+// funnel runs the simple stupid funnel algorithm over portals given as
+// left, right pairs. The first and last portals hold the start and end.
+func funnel(portals [][2]rl.Vector3) []rl.Vector3 {
+	apex, left, right := portals[0][0], portals[0][0], portals[0][1]
+	apexIndex, leftIndex, rightIndex := 0, 0, 0
+	path := []rl.Vector3{apex}
+
+	for i := 1; i < len(portals); i++ {
+		l, r := portals[i][0], portals[i][1]
+
+		// Narrow the right side unless it crosses over the left one
+		if cross2D(apex, right, r) >= 0 {
+			if apex == right || cross2D(apex, left, r) < 0 {
+				right, rightIndex = r, i
+			} else {
+				apex, apexIndex = left, leftIndex
+				path = append(path, apex)
+				left, right = apex, apex
+				leftIndex, rightIndex = apexIndex, apexIndex
+				i = apexIndex
+				continue
+			}
+		}
+
+		// Narrow the left side unless it crosses over the right one
+		if cross2D(apex, left, l) <= 0 {
+			if apex == left || cross2D(apex, right, l) > 0 {
+				left, leftIndex = l, i
+			} else {
+				apex, apexIndex = right, rightIndex
+				path = append(path, apex)
+				left, right = apex, apex
+				leftIndex, rightIndex = apexIndex, apexIndex
+				i = apexIndex
+				continue
+			}
+		}
+	}
+
+	end := portals[len(portals)-1][0]
+	if path[len(path)-1] != end {
+		path = append(path, end)
+	}
+	return path
+}
+
+// portalPoint returns the point of edge a, b where the shortest route from
+// from to to through the edge crosses it, ignoring everything else.
+func portalPoint(a, b, from, to rl.Vector3) rl.Vector3 {
+	// Mirror to onto the side of from, then cross where the straight line
+	// between the two meets the edge
+	if (cross2D(a, b, from) > 0) == (cross2D(a, b, to) > 0) {
+		projected := closestOnLine2D(a, b, to)
+		to.X, to.Z = 2*projected.X-to.X, 2*projected.Z-to.Z
+	}
+	sideFrom, sideTo := cross2D(a, b, from), cross2D(a, b, to)
+	if sideFrom == sideTo {
+		return closestOnSegment2D(a, b, from)
+	}
+	crossing := rl.Vector3Lerp(from, to, sideFrom/(sideFrom-sideTo))
+	return closestOnSegment2D(a, b, crossing)
+}
+
+// cross2D is positive when c lies to the left of the line from a to b on
+// the X/Z plane.
+func cross2D(a, b, c rl.Vector3) float32 {
+	return (b.X-a.X)*(c.Z-a.Z) - (b.Z-a.Z)*(c.X-a.X)
+}
+
+func distance2D(a, b rl.Vector3) float32 {
+	return float32(math.Hypot(float64(a.X-b.X), float64(a.Z-b.Z)))
+}
+
+// closestOnSegment2D returns the point of segment a, b closest to pos on the
+// X/Z plane, with its height taken from the segment.
+func closestOnSegment2D(a, b, pos rl.Vector3) rl.Vector3 {
+	return rl.Vector3Lerp(a, b, min(max(lineParameter2D(a, b, pos), 0), 1))
+}
+
+// closestOnLine2D is closestOnSegment2D for the whole line through a and b.
+func closestOnLine2D(a, b, pos rl.Vector3) rl.Vector3 {
+	return rl.Vector3Lerp(a, b, lineParameter2D(a, b, pos))
+}
+
+// lineParameter2D projects pos onto the line through a and b, returning 0 at
+// a and 1 at b.
+func lineParameter2D(a, b, pos rl.Vector3) float32 {
+	dx, dz := b.X-a.X, b.Z-a.Z
+	length := dx*dx + dz*dz
+	if length == 0 {
+		return 0
+	}
+	return ((pos.X-a.X)*dx + (pos.Z-a.Z)*dz) / length
+}
@@ -0,0 +1,145 @@
+package pathfinder
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// newPillarMesh returns a 10 by 10 mesh with a 2 by 2 pillar in its middle.
+func newPillarMesh() NavMesh {
+	return BuildNavMesh(NavMeshConfig{
+		Min: rl.NewVector3(0, 0, 0),
+		Max: rl.NewVector3(10, 0, 10),
+	}, rl.NewBoundingBox(rl.NewVector3(4, 0, 4), rl.NewVector3(6, 2, 6)))
+}
+
+func TestNavMeshSaveLoad(t *testing.T) {
+	mesh := newPillarMesh()
+	path := filepath.Join(t.TempDir(), "pillar.navmesh.json")
+	if err := mesh.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadNavMesh(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(loaded.GetVertices(), mesh.GetVertices()) {
+		t.Errorf("loaded vertices %v, want %v", loaded.GetVertices(), mesh.GetVertices())
+	}
+	if !reflect.DeepEqual(loaded.GetPolygons(), mesh.GetPolygons()) {
+		t.Errorf("loaded polygons %v, want %v", loaded.GetPolygons(), mesh.GetPolygons())
+	}
+	start, target := rl.NewVector3(1, 0, 5), rl.NewVector3(9, 0, 5)
+	if got, want := loaded.FindPath(start, target), mesh.FindPath(start, target); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded mesh path %+v, want %+v", got, want)
+	}
+}
+
+func TestNavMeshLoadErrors(t *testing.T) {
+	if _, err := LoadNavMesh(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("loading a missing file succeeded")
+	}
+}
+
+func TestNavMeshLoadOrBuild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "pillar.navmesh.json")
+	config := NavMeshConfig{Min: rl.NewVector3(0, 0, 0), Max: rl.NewVector3(10, 0, 10)}
+	pillar := rl.NewBoundingBox(rl.NewVector3(4, 0, 4), rl.NewVector3(6, 2, 6))
+
+	built, err := LoadOrBuildNavMesh(path, config, pillar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Spoil the baked vertices to tell a load from a rebuild
+	baked, _ := LoadNavMesh(path)
+	baked.(*navMesh).Vertices[0].X = -1
+	if err := baked.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, _ := LoadOrBuildNavMesh(path, config, pillar); loaded.GetVertices()[0].X != -1 {
+		t.Errorf("rebuilt a mesh baked for the same obstacles")
+	}
+
+	for name, rebake := range map[string]func(){
+		"obstacle": func() { pillar.Max.X = 7 },
+		"radius":   func() { config.AgentRadius = 0.5 },
+		"terrain":  func() { config.Height = func(x, z float32) float32 { return x / 10 } },
+	} {
+		rebake()
+		mesh, err := LoadOrBuildNavMesh(path, config, pillar)
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved, _ := LoadNavMesh(path)
+		if mesh.GetKey() == built.GetKey() || saved.GetKey() != mesh.GetKey() || saved.GetVertices()[0].X == -1 {
+			t.Errorf("%s: stale mesh kept after the change", name)
+		}
+		built = mesh
+	}
+}
+
+func TestNavMeshFunnel(t *testing.T) {
+	mesh := newPillarMesh()
+	for _, c := range []struct {
+		name          string
+		start, target rl.Vector3
+		// corners are the pillar corners the path turns at
+		corners int
+		cost    float64
+	}{
+		{"straight", rl.NewVector3(1, 0, 1), rl.NewVector3(9, 0, 1), 0, 8},
+		{"diagonal", rl.NewVector3(1, 0, 9), rl.NewVector3(3, 0, 1), 0, math.Sqrt(68)},
+		{"around pillar", rl.NewVector3(1, 0, 5), rl.NewVector3(9, 0, 5), 2, 2*math.Sqrt(10) + 2},
+		{"past corner", rl.NewVector3(5, 0, 1), rl.NewVector3(7, 0, 9), 1, math.Sqrt(10) + math.Sqrt(26)},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			result := mesh.FindPath(c.start, c.target)
+			if result.Status != StatusFound {
+				t.Fatalf("status %s, want found", result.Status)
+			}
+			if len(result.Path) != c.corners+2 {
+				t.Fatalf("path %v turns %d times, want %d", result.Path, len(result.Path)-2, c.corners)
+			}
+			if result.Path[0] != c.start || result.Path[len(result.Path)-1] != c.target {
+				t.Errorf("path %v does not run from %v to %v", result.Path, c.start, c.target)
+			}
+			for _, corner := range result.Path[1 : len(result.Path)-1] {
+				if (corner.X != 4 && corner.X != 6) || (corner.Z != 4 && corner.Z != 6) {
+					t.Errorf("path turns at %v, off the pillar corners", corner)
+				}
+			}
+			if math.Abs(result.Cost-c.cost) > 1e-4 {
+				t.Errorf("cost %.6f, want %.6f", result.Cost, c.cost)
+			}
+		})
+	}
+}
+
+func TestFunnelPortals(t *testing.T) {
+	start, end := rl.NewVector3(0, 0, 0), rl.NewVector3(5, 0, 4)
+	// A corridor heading along +X, then turning toward +Z around the
+	// corner at (2, 0, 2) on its left
+	portals := [][2]rl.Vector3{
+		{start, start},
+		{rl.NewVector3(1, 0, 2), rl.NewVector3(1, 0, -2)},
+		{rl.NewVector3(2, 0, 2), rl.NewVector3(3, 0, -2)},
+		{rl.NewVector3(2, 0, 3), rl.NewVector3(6, 0, 3)},
+		{end, end},
+	}
+	want := []rl.Vector3{start, end}
+	if got := funnel(portals); !reflect.DeepEqual(got, want) {
+		t.Errorf("funnel %v, want %v", got, want)
+	}
+
+	end = rl.NewVector3(2.5, 0, 6)
+	portals[len(portals)-1] = [2]rl.Vector3{end, end}
+	want = []rl.Vector3{start, rl.NewVector3(2, 0, 2), end}
+	if got := funnel(portals); !reflect.DeepEqual(got, want) {
+		t.Errorf("funnel %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package pathfinder
+
+import (
+	"sync"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const nodeChunkSize = 1024
+
+// nodePool hands out nodes from reusable chunks so a search does not
+// allocate every node it discovers.
+type nodePool struct {
+	chunks [][]Node
+	chunk  int
+	next   int
+}
+
+func (p *nodePool) get() *Node {
+	if p.chunk == len(p.chunks) {
+		p.chunks = append(p.chunks, make([]Node, nodeChunkSize))
+	}
+	node := &p.chunks[p.chunk][p.next]
+	p.next++
+	if p.next == nodeChunkSize {
+		p.chunk++
+		p.next = 0
+	}
+	*node = Node{index: -1}
+	return node
+}
+
+// each calls f with every node handed out since the last reset.
+func (p *nodePool) each(f func(node *Node)) {
+	for chunk := 0; chunk <= p.chunk && chunk < len(p.chunks); chunk++ {
+		count := nodeChunkSize
+		if chunk == p.chunk {
+			count = p.next
+		}
+		for i := 0; i < count; i++ {
+			f(&p.chunks[chunk][i])
+		}
+	}
+}
+
+func (p *nodePool) reset() {
+	p.chunk = 0
+	p.next = 0
+}
+
+// searchState holds the scratch memory of a single search: every node
+// discovered so far indexed by cell, the open set and the neighbour buffer.
+type searchState struct {
+	nodes map[Cell]*Node
+	// columns indexes the nodes by column instead of nodes for searches on
+	// the ground, which hold a single node per column
+	columns   []*Node
+	width     int
+	openSet   PriorityQueue
+	neighbors []Node
+	pool      nodePool
+}
+
+var searchStates = sync.Pool{
+	New: func() interface{} {
+		return &searchState{nodes: make(map[Cell]*Node)}
+	},
+}
+
+func acquireSearch() *searchState {
+	return searchStates.Get().(*searchState)
+}
+
+func releaseSearch(search *searchState) {
+	if search.width > 0 {
+		search.pool.each(func(node *Node) {
+			search.columns[node.cell.Z*search.width+node.cell.X] = nil
+		})
+		search.width = 0
+	}
+	clear(search.nodes)
+	search.openSet = search.openSet[:0]
+	search.neighbors = search.neighbors[:0]
+	search.pool.reset()
+	searchStates.Put(search)
+}
+
+// onGround indexes the nodes of the search by column of grid. It is called
+// before the first node is added.
+func (s *searchState) onGround(grid NavGrid) {
+	size := grid.GetSize()
+	if len(s.columns) < size.X*size.Z {
+		s.columns = make([]*Node, size.X*size.Z)
+	}
+	s.width = size.X
+}
+
+// add registers a newly discovered node for cell.
+func (s *searchState) add(cell Cell, position rl.Vector3) *Node {
+	node := s.pool.get()
+	node.cell = cell
+	node.position = position
+	if s.width > 0 {
+		s.columns[cell.Z*s.width+cell.X] = node
+	} else {
+		s.nodes[cell] = node
+	}
+	return node
+}
+
+// get returns the node discovered for cell, or nil.
+func (s *searchState) get(cell Cell) *Node {
+	if s.width > 0 {
+		return s.columns[cell.Z*s.width+cell.X]
+	}
+	return s.nodes[cell]
+}
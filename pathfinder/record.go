@@ -0,0 +1,72 @@
+package pathfinder
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// SearchNode is a node as it was when a recorded search touched it.
+type SearchNode struct {
+	Cell     Cell
+	Position rl.Vector3
+	GCost    float64
+	HCost    float64
+}
+
+// SearchStep is one expansion of a recorded search: the node taken off the
+// open set and the neighbours it opened or improved.
+type SearchStep struct {
+	Expanded SearchNode
+	Opened   []SearchNode
+}
+
+// Recording holds every step of a search so it can be replayed.
+type Recording struct {
+	Start  rl.Vector3
+	Target rl.Vector3
+	Steps  []SearchStep
+	Result Result
+}
+
+// RecordSearch runs FindPath and records each of its expansions.
+func RecordSearch(grid NavGrid, start, target rl.Vector3, opts Options) *Recording {
+	recording := &Recording{Start: start, Target: target}
+	opts.recording = recording
+	recording.Result = FindPath(grid, start, target, opts)
+	return recording
+}
+
+// SetsAt returns the open and closed sets after the first step expansions.
+func (r *Recording) SetsAt(step int) (open, closed map[Cell]SearchNode) {
+	open = make(map[Cell]SearchNode)
+	closed = make(map[Cell]SearchNode)
+	for _, s := range r.Steps[:min(max(step, 0), len(r.Steps))] {
+		delete(open, s.Expanded.Cell)
+		closed[s.Expanded.Cell] = s.Expanded
+		for _, node := range s.Opened {
+			open[node.Cell] = node
+		}
+	}
+	return open, closed
+}
+
+// expand starts a new step at node. Searches call it on a nil recording
+// when nothing is recorded.
+func (r *Recording) expand(node *Node) {
+	if r == nil {
+		return
+	}
+	r.Steps = append(r.Steps, SearchStep{Expanded: searchNode(node)})
+}
+
+// open adds node to the current step.
+func (r *Recording) open(node *Node) {
+	if r == nil || len(r.Steps) == 0 {
+		return
+	}
+	step := &r.Steps[len(r.Steps)-1]
+	step.Opened = append(step.Opened, searchNode(node))
+}
+
+func searchNode(node *Node) SearchNode {
+	return SearchNode{Cell: node.cell, Position: node.position, GCost: node.gCost, HCost: node.hCost}
+}
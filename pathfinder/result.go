@@ -0,0 +1,160 @@
+package pathfinder
+
+import (
+	"errors"
+	cts "main/constants"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Errors reported in Result.Err when a search does not reach its goal.
+var (
+	ErrOutOfBounds = errors.New("pathfinder: position is outside the navigation grid")
+	ErrBlocked     = errors.New("pathfinder: goal is blocked")
+	ErrUnreachable = errors.New("pathfinder: goal is unreachable")
+	ErrNodeLimit   = errors.New("pathfinder: expanded node limit reached")
+	ErrReserved    = errors.New("pathfinder: every move is reserved by other agents")
+	ErrCacheGrid   = errors.New("pathfinder: path cache belongs to another grid")
+)
+
+// Status describes how a search ended.
+type Status int
+
+const (
+	// StatusFound means the path reaches the goal
+	StatusFound Status = iota
+	// StatusPartial means the path stops at the reachable cell closest to the goal
+	StatusPartial
+	// StatusUnreachable means no path was produced
+	StatusUnreachable
+	// StatusCanceled means the search context ended before the search did
+	StatusCanceled
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusFound:
+		return "found"
+	case StatusPartial:
+		return "partial"
+	case StatusCanceled:
+		return "canceled"
+	default:
+		return "unreachable"
+	}
+}
+
+// Result is the outcome of a path query.
+type Result struct {
+	Status Status
+	// Path holds the waypoints from start to goal, or to the closest reachable cell for partial results
+	Path []rl.Vector3
+	// Cost is the accumulated cost of Path
+	Cost float64
+	// Expanded counts the nodes taken off the open set
+	Expanded int
+	// Err explains why the goal was not reached, nil when Status is StatusFound
+	Err error
+}
+
+// Mode selects the space a search moves through.
+type Mode int
+
+const (
+	// Mode3D moves through the full voxel grid, for flying units
+	Mode3D Mode = iota
+	// ModeGround moves on the XZ plane with Y snapped to the terrain
+	ModeGround
+)
+
+// Connectivity selects the ground moves available from a cell.
+type Connectivity int
+
+const (
+	// Connect8 allows straight and diagonal moves
+	Connect8 Connectivity = iota
+	// Connect4 allows straight moves only
+	Connect4
+)
+
+// CornerRule decides when a diagonal move may squeeze past blocked cells.
+type CornerRule int
+
+const (
+	// CornerNever requires every cell beside a diagonal move to be free
+	CornerNever CornerRule = iota
+	// CornerOneFree requires at least one straight neighbour to be free
+	CornerOneFree
+	// CornerAlways allows diagonal moves regardless of their neighbours
+	CornerAlways
+)
+
+// Algorithm selects the search run on the grid.
+type Algorithm int
+
+const (
+	// AlgorithmAStar expands every neighbour of each node
+	AlgorithmAStar Algorithm = iota
+	// AlgorithmJPS jumps along straight and diagonal runs of uniform-cost
+	// ground cells. Queries it cannot answer fall back to A*.
+	AlgorithmJPS
+)
+
+// Options configures a path query.
+type Options struct {
+	Algorithm    Algorithm
+	Mode         Mode
+	Connectivity Connectivity
+	Corners      CornerRule
+	// Heuristic guides the search, nil means Euclidean
+	Heuristic Heuristic
+	// Weight scales the heuristic; values above 1 trade path quality for
+	// speed, 0 means 1
+	Weight float64
+	// Cost charges each step, nil means the distance travelled
+	Cost CostFunc
+	// CostMap scales the distance of each step by the terrain it crosses
+	CostMap CostMap
+	// MaxStepHeight is the highest rise or drop between neighbouring ground
+	// cells, 0 means no limit
+	MaxStepHeight float32
+	// MaxSlope is the steepest ground step in degrees, 0 means no limit
+	MaxSlope float32
+	// ClimbCost is added to a ground step for each unit it rises
+	ClimbCost float64
+	// AgentRadius keeps ground agents on cells with at least that much
+	// clearance, 0 treats the agent as a point
+	AgentRadius float32
+	// StringPull drops waypoints that are in line of sight of each other
+	StringPull bool
+	// Curve rounds the path corners after string pulling
+	Curve Curve
+	// CurveSegments is the number of samples per curved segment, 0 means a default of 4
+	CurveSegments int
+	// MaxNodes caps the number of expanded nodes, 0 means no limit
+	MaxNodes int
+	// AllowPartial returns the path to the closest reachable cell when the goal cannot be reached
+	AllowPartial bool
+	// Profile names the kind of agent asking, path caches keep the paths of
+	// each profile apart. Queries with a Cost function need one to be cached
+	Profile string
+	// recording receives every expansion when the search is run by RecordSearch
+	recording *Recording
+}
+
+// DefaultOptions returns the options agents use unless told otherwise
+func DefaultOptions() Options {
+	return Options{
+		Mode:          ModeGround,
+		Connectivity:  Connect8,
+		Corners:       CornerNever,
+		Heuristic:     Octile{},
+		Weight:        1,
+		MaxStepHeight: cts.MaxStepHeight,
+		MaxSlope:      cts.MaxSlope,
+		ClimbCost:     cts.ClimbCost,
+		StringPull:    true,
+		MaxNodes:      cts.MaxExpandedNodes,
+		AllowPartial:  true,
+	}
+}
@@ -0,0 +1,172 @@
+package pathfinder
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Errors returned by Service.Submit.
+var (
+	ErrServiceClosed = errors.New("pathfinder: service is closed")
+	ErrQueueFull     = errors.New("pathfinder: request queue is full")
+)
+
+// Request is a path query handed to a Service.
+type Request struct {
+	Grid    NavGrid
+	Start   rl.Vector3
+	Target  rl.Vector3
+	Options Options
+	// Cache, when not nil, answers the request instead of searching Grid. It
+	// must have been created for Grid
+	Cache PathCache
+	// NavMesh, when not nil, answers the request instead of Grid and Cache,
+	// ignoring the terrain costs, agent size and height limits of Options
+	NavMesh NavMesh
+	// Done receives the result on the goroutine calling Service.Dispatch, or
+	// Service.Close when the service stops first
+	Done func(Result)
+}
+
+// Service runs path queries on a pool of worker goroutines so long searches
+// never stall the game loop. Results are queued until the game loop calls
+// Dispatch, which runs the Done callback of each finished request. Every
+// accepted request gets exactly one call to Done.
+type Service interface {
+	Submit(ctx context.Context, request Request) error
+	Dispatch() int
+	Close()
+}
+
+type job struct {
+	ctx     context.Context
+	request Request
+}
+
+type completion struct {
+	request Request
+	result  Result
+}
+
+type service struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	jobs      chan job
+	workers   sync.WaitGroup
+	mu        sync.Mutex
+	completed []completion
+	closed    bool
+}
+
+// NewService creates a new instance of Service with the given number of
+// workers and room for queueSize pending requests
+func NewService(workers, queueSize int) Service {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &service{
+		ctx:    ctx,
+		cancel: cancel,
+		jobs:   make(chan job, queueSize),
+	}
+	for i := 0; i < workers; i++ {
+		s.workers.Add(1)
+		go s.work()
+	}
+	return s
+}
+
+// Submit queues request without blocking. ctx cancels the search or bounds
+// it with a deadline; a canceled request still reports back through Done.
+func (s *service) Submit(ctx context.Context, request Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrServiceClosed
+	}
+	select {
+	case s.jobs <- job{ctx: ctx, request: request}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Dispatch hands every finished result to its callback and returns how many
+// were delivered. Call it once per frame from the game loop.
+func (s *service) Dispatch() int {
+	s.mu.Lock()
+	completed := s.completed
+	s.completed = nil
+	s.mu.Unlock()
+
+	for _, c := range completed {
+		if c.request.Done != nil {
+			c.request.Done(c.result)
+		}
+	}
+	return len(completed)
+}
+
+// Close cancels the searches in flight and waits for the workers to stop.
+// Requests whose results were not dispatched yet are reported to their Done
+// callback as canceled, so call it from the goroutine calling Dispatch.
+func (s *service) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.jobs)
+	s.mu.Unlock()
+
+	s.cancel()
+	s.workers.Wait()
+
+	s.mu.Lock()
+	completed := s.completed
+	s.completed = nil
+	s.mu.Unlock()
+	// Without workers the queued requests were never picked up
+	for j := range s.jobs {
+		completed = append(completed, completion{request: j.request})
+	}
+	for _, c := range completed {
+		if c.request.Done != nil {
+			c.request.Done(Result{Status: StatusCanceled, Err: ErrServiceClosed})
+		}
+	}
+}
+
+func (s *service) work() {
+	defer s.workers.Done()
+	for j := range s.jobs {
+		result := s.run(j)
+		s.mu.Lock()
+		s.completed = append(s.completed, completion{request: j.request, result: result})
+		s.mu.Unlock()
+	}
+}
+
+// run answers a single job, stopping early when either its own context or
+// the service is canceled.
+func (s *service) run(j job) Result {
+	ctx, cancel := context.WithCancel(j.ctx)
+	defer cancel()
+	stop := context.AfterFunc(s.ctx, cancel)
+	defer stop()
+
+	if err := ctx.Err(); err != nil {
+		return Result{Status: StatusCanceled, Err: err}
+	}
+	r := j.request
+	if r.NavMesh != nil {
+		return r.NavMesh.FindPath(r.Start, r.Target)
+	}
+	if r.Cache != nil {
+		return findPathCached(ctx, r.Cache, r.Grid, r.Start, r.Target, r.Options)
+	}
+	return FindPathContext(ctx, r.Grid, r.Start, r.Target, r.Options)
+}
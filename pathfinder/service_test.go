@@ -0,0 +1,166 @@
+package pathfinder
+
+import (
+	"context"
+	"errors"
+	cts "main/constants"
+	"testing"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// dispatchUntil calls Dispatch until done reports true, failing after a
+// few seconds.
+func dispatchUntil(t *testing.T, s Service, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("results were not delivered in time")
+		}
+		s.Dispatch()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// newSealedGrid returns a large open grid whose far corner is walled off, so
+// a search for it expands every other cell.
+func newSealedGrid() (NavGrid, rl.Vector3, rl.Vector3) {
+	const size = 512
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: size, Y: 1, Z: size})
+	for i := size - 3; i < size; i++ {
+		grid.SetBlocked(Cell{X: size - 3, Z: i}, true)
+		grid.SetBlocked(Cell{X: i, Z: size - 3}, true)
+	}
+	return grid, rl.NewVector3(0, 0, 0), rl.NewVector3(size-1, 0, size-1)
+}
+
+func TestServiceDelivers(t *testing.T) {
+	s := NewService(4, 16)
+	defer s.Close()
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 32, Y: 1, Z: 32})
+	opts := DefaultOptions()
+
+	results := make([]Result, 16)
+	delivered := 0
+	for i := range results {
+		i := i
+		target := rl.NewVector3(float32(i+10), 0, 20)
+		err := s.Submit(context.Background(), Request{
+			Grid: grid, Start: rl.NewVector3(1, 0, 1), Target: target, Options: opts,
+			Done: func(result Result) {
+				results[i] = result
+				delivered++
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	dispatchUntil(t, s, func() bool { return delivered == len(results) })
+	for i, result := range results {
+		want := FindPath(grid, rl.NewVector3(1, 0, 1), rl.NewVector3(float32(i+10), 0, 20), opts)
+		if result.Status != StatusFound || result.Cost != want.Cost {
+			t.Errorf("request %d: %s at cost %.4f, want found at %.4f", i, result.Status, result.Cost, want.Cost)
+		}
+	}
+}
+
+func TestServiceCanceled(t *testing.T) {
+	s := NewService(1, 4)
+	defer s.Close()
+	grid, start, target := newSealedGrid()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var got *Result
+	err := s.Submit(ctx, Request{Grid: grid, Start: start, Target: target, Options: DefaultOptions(),
+		Done: func(result Result) { got = &result },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatchUntil(t, s, func() bool { return got != nil })
+	if got.Status != StatusCanceled || !errors.Is(got.Err, context.Canceled) {
+		t.Errorf("%s with error %v, want canceled", got.Status, got.Err)
+	}
+}
+
+func TestServiceTimeout(t *testing.T) {
+	s := NewService(1, 4)
+	defer s.Close()
+	grid, start, target := newSealedGrid()
+	opts := DefaultOptions()
+	opts.MaxNodes = 0
+
+	// Searching the whole sealed grid takes far longer than a millisecond,
+	// the game bounds its queries with PathTimeout the same way
+	timeout := min(cts.PathTimeout, time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var got *Result
+	err := s.Submit(ctx, Request{Grid: grid, Start: start, Target: target, Options: opts,
+		Done: func(result Result) { got = &result },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatchUntil(t, s, func() bool { return got != nil })
+	if got.Status != StatusCanceled || !errors.Is(got.Err, context.DeadlineExceeded) {
+		t.Errorf("%s with error %v, want canceled by the deadline", got.Status, got.Err)
+	}
+}
+
+func TestServiceQueueFull(t *testing.T) {
+	// Without workers nothing leaves the queue
+	s := NewService(0, 2)
+	var closed []Result
+	request := Request{Done: func(result Result) { closed = append(closed, result) }}
+	for i := 0; i < 2; i++ {
+		if err := s.Submit(context.Background(), request); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if err := s.Submit(context.Background(), request); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Submit on a full queue returned %v, want ErrQueueFull", err)
+	}
+
+	s.Close()
+	if len(closed) != 2 {
+		t.Fatalf("Close reported %d queued requests, want 2", len(closed))
+	}
+	for _, result := range closed {
+		if result.Status != StatusCanceled || !errors.Is(result.Err, ErrServiceClosed) {
+			t.Errorf("queued request ended %s with error %v, want canceled", result.Status, result.Err)
+		}
+	}
+	if err := s.Submit(context.Background(), request); !errors.Is(err, ErrServiceClosed) {
+		t.Errorf("Submit after Close returned %v, want ErrServiceClosed", err)
+	}
+}
+
+func TestAgentSyncDropsAsync(t *testing.T) {
+	s := NewService(1, 4)
+	defer s.Close()
+	grid := NewNavGrid(rl.Vector3{}, 1, Cell{X: 32, Y: 1, Z: 32})
+	agent := NewAgent(grid, rl.NewVector3(1, 0, 1), 1)
+
+	called := false
+	if err := agent.FindPathAsync(context.Background(), s, rl.NewVector3(30, 0, 1), func(Result) { called = true }); err != nil {
+		t.Fatal(err)
+	}
+	want := agent.FindPath(rl.NewVector3(1, 0, 30))
+	if agent.IsPending() {
+		t.Error("agent still waits for the asynchronous request")
+	}
+	// Give the worker time to answer, then deliver whatever it produced
+	time.Sleep(20 * time.Millisecond)
+	s.Dispatch()
+	if called {
+		t.Error("the replaced asynchronous request reported its result")
+	}
+	if path := agent.GetPath(); len(path) != len(want.Path) || path[len(path)-1] != want.Path[len(want.Path)-1] {
+		t.Errorf("agent follows %v, want the synchronous path %v", path, want.Path)
+	}
+}
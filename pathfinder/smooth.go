@@ -0,0 +1,176 @@
+package pathfinder
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Curve selects how a path is rounded after the search.
+type Curve int
+
+const (
+	// CurveNone keeps the straight segments between waypoints
+	CurveNone Curve = iota
+	// CurveCatmullRom passes a Catmull-Rom spline through every waypoint
+	CurveCatmullRom
+	// CurveBezier rounds each corner with a quadratic Bezier between segment midpoints
+	CurveBezier
+)
+
+// defaultCurveSegments is used when Options.CurveSegments is not set.
+const defaultCurveSegments = 4
+
+// postProcess applies the smoothing configured in opts to a path found on grid.
+func postProcess(grid NavGrid, path []rl.Vector3, opts Options) []rl.Vector3 {
+	if opts.StringPull {
+		path = StringPull(grid, path, opts)
+	}
+	segments := opts.CurveSegments
+	if segments <= 0 {
+		segments = defaultCurveSegments
+	}
+
+	var curved []rl.Vector3
+	switch opts.Curve {
+	case CurveCatmullRom:
+		curved = CatmullRom(path, segments)
+	case CurveBezier:
+		curved = Bezier(path, segments)
+	default:
+		return path
+	}
+	if opts.Mode == ModeGround {
+		for i := range curved {
+			curved[i].Y = grid.GetGroundHeight(curved[i].X, curved[i].Z)
+		}
+	}
+	// Curves bulge around corners; keep the straight path if that would clip an obstacle
+	for i := 1; i < len(curved); i++ {
+		if !HasLineOfSight(grid, curved[i-1], curved[i], opts) {
+			return path
+		}
+	}
+	return curved
+}
+
+// StringPull removes every waypoint that can be skipped because the
+// waypoints around it see each other in a straight line.
+func StringPull(grid NavGrid, path []rl.Vector3, opts Options) []rl.Vector3 {
+	if len(path) < 3 {
+		return path
+	}
+	pulled := []rl.Vector3{path[0]}
+	anchor := 0
+	for i := 2; i < len(path); i++ {
+		if !HasLineOfSight(grid, path[anchor], path[i], opts) {
+			anchor = i - 1
+			pulled = append(pulled, path[anchor])
+		}
+	}
+	return append(pulled, path[len(path)-1])
+}
+
+// HasLineOfSight reports whether the straight segment from a to b crosses
+// only walkable cells with room for opts.AgentRadius. Diagonal cell changes
+// also need their straight neighbours free, the same way CornerNever treats
+// diagonal steps. With a cost map every cell must also cost the same as the
+// first one, so shortcuts never leave a road for a swamp. On the ground
+// every cell change must also respect the height limits of opts.
+func HasLineOfSight(grid NavGrid, a, b rl.Vector3, opts Options) bool {
+	cellAt := func(pos rl.Vector3) Cell {
+		if opts.Mode == ModeGround {
+			cell := grid.WorldToCell(pos)
+			return getGroundNode(grid, cell.X, cell.Z).cell
+		}
+		return grid.WorldToCell(pos)
+	}
+
+	// Sample at a quarter cell so no cell along the segment is skipped
+	distance := rl.Vector3Distance(a, b)
+	steps := int(math.Ceil(float64(distance/grid.GetCellSize()*4))) + 1
+	previous := cellAt(a)
+	if !canStand(grid, previous, opts) {
+		return false
+	}
+	sameCost := func(cell Cell) bool {
+		return opts.CostMap == nil || opts.CostMap.GetCost(cell.X, cell.Z) == opts.CostMap.GetCost(previous.X, previous.Z)
+	}
+	for i := 1; i <= steps; i++ {
+		cell := cellAt(rl.Vector3Lerp(a, b, float32(i)/float32(steps)))
+		if cell == previous {
+			continue
+		}
+		if !canStand(grid, cell, opts) || !sameCost(cell) {
+			return false
+		}
+		if opts.Mode == ModeGround {
+			if !canStep(getGroundNode(grid, previous.X, previous.Z).position, getGroundNode(grid, cell.X, cell.Z).position, opts) {
+				return false
+			}
+			if cell.X != previous.X && cell.Z != previous.Z &&
+				(!canStand(grid, getGroundNode(grid, cell.X, previous.Z).cell, opts) ||
+					!canStand(grid, getGroundNode(grid, previous.X, cell.Z).cell, opts)) {
+				return false
+			}
+		} else {
+			delta := Cell{cell.X - previous.X, cell.Y - previous.Y, cell.Z - previous.Z}
+			if !canCutCorner(grid, previous, delta, CornerNever) {
+				return false
+			}
+		}
+		previous = cell
+	}
+	return true
+}
+
+// CatmullRom returns a uniform Catmull-Rom spline through every point of
+// path with segments samples between consecutive points.
+func CatmullRom(path []rl.Vector3, segments int) []rl.Vector3 {
+	if len(path) < 3 || segments < 2 {
+		return path
+	}
+	curve := make([]rl.Vector3, 0, (len(path)-1)*segments+1)
+	for i := 0; i < len(path)-1; i++ {
+		p0 := path[max(i-1, 0)]
+		p1 := path[i]
+		p2 := path[i+1]
+		p3 := path[min(i+2, len(path)-1)]
+		for s := 0; s < segments; s++ {
+			curve = append(curve, catmullRomPoint(p0, p1, p2, p3, float32(s)/float32(segments)))
+		}
+	}
+	return append(curve, path[len(path)-1])
+}
+
+// Bezier rounds every inner corner of path with a quadratic Bezier that
+// starts and ends at the midpoints of the segments meeting there.
+func Bezier(path []rl.Vector3, segments int) []rl.Vector3 {
+	if len(path) < 3 || segments < 2 {
+		return path
+	}
+	curve := []rl.Vector3{path[0]}
+	for i := 1; i < len(path)-1; i++ {
+		from := rl.Vector3Lerp(path[i-1], path[i], 0.5)
+		to := rl.Vector3Lerp(path[i], path[i+1], 0.5)
+		for s := 0; s <= segments; s++ {
+			t := float32(s) / float32(segments)
+			curve = append(curve, rl.Vector3Lerp(rl.Vector3Lerp(from, path[i], t), rl.Vector3Lerp(path[i], to, t), t))
+		}
+	}
+	return append(curve, path[len(path)-1])
+}
+
+// catmullRomPoint evaluates the spline segment between p1 and p2 at t.
+func catmullRomPoint(p0, p1, p2, p3 rl.Vector3, t float32) rl.Vector3 {
+	t2 := t * t
+	t3 := t2 * t
+	component := func(a, b, c, d float32) float32 {
+		return 0.5 * (2*b + (c-a)*t + (2*a-5*b+4*c-d)*t2 + (3*b-a-3*c+d)*t3)
+	}
+	return rl.NewVector3(
+		component(p0.X, p1.X, p2.X, p3.X),
+		component(p0.Y, p1.Y, p2.Y, p3.Y),
+		component(p0.Z, p1.Z, p2.Z, p3.Z),
+	)
+}
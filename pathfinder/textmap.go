@@ -0,0 +1,101 @@
+package pathfinder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// TextMap is a ground grid read from a text map. Each line is a row along Z
+// and each character a cell along X:
+//
+//	.    walkable
+//	#    blocked
+//	S G  walkable start and goal
+//	1-9  walkable with that cost multiplier
+//
+// Cells are one unit wide with the first cell centred on the origin.
+type TextMap struct {
+	Grid  NavGrid
+	Start rl.Vector3
+	Goal  rl.Vector3
+	// Costs holds the digit multipliers, nil when the map has none
+	Costs CostMap
+}
+
+// LoadTextMap reads the text map at path, see TextMap.
+func LoadTextMap(path string) (*TextMap, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	m, err := ParseTextMap(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// ParseTextMap reads a text map, see TextMap. Blank lines are skipped.
+func ParseTextMap(r io.Reader) (*TextMap, error) {
+	var rows []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if row := strings.TrimRight(scanner.Text(), " \t\r"); row != "" {
+			rows = append(rows, row)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("pathfinder: text map is empty")
+	}
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	m := &TextMap{Grid: NewNavGrid(rl.NewVector3(0, 0, 0), 1, Cell{X: width, Y: 1, Z: len(rows)})}
+	costs := NewCostMap(width, len(rows), 1)
+	weighted, starts, goals := false, 0, 0
+
+	for z, row := range rows {
+		for x := 0; x < width; x++ {
+			char := byte('#') // Short rows are padded with walls
+			if x < len(row) {
+				char = row[x]
+			}
+			cell := Cell{X: x, Z: z}
+			switch {
+			case char == '.':
+			case char == '#':
+				m.Grid.SetBlocked(cell, true)
+			case char == 'S':
+				m.Start = m.Grid.CellToWorld(cell)
+				starts++
+			case char == 'G':
+				m.Goal = m.Grid.CellToWorld(cell)
+				goals++
+			case char >= '1' && char <= '9':
+				costs.SetCost(x, z, float32(char-'0'))
+				weighted = weighted || char != '1'
+			default:
+				return nil, fmt.Errorf("pathfinder: text map row %d has unknown cell %q", z+1, char)
+			}
+		}
+	}
+	if starts != 1 || goals != 1 {
+		return nil, fmt.Errorf("pathfinder: text map needs one S and one G, has %d and %d", starts, goals)
+	}
+	if weighted {
+		m.Costs = costs
+	}
+	return m, nil
+}
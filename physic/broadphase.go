@@ -0,0 +1,274 @@
+package physics
+
+import (
+	"math"
+	"slices"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// maxHashCells is the most cells a body may cover in a spatial hash. Larger
+// bodies, or bodies with no finite bounds, are tested against every body
+// instead of walking the cells they cover.
+const maxHashCells = 64
+
+// AABB type, an axis aligned bounding box
+type AABB struct {
+	Min rl.Vector2
+	Max rl.Vector2
+}
+
+// Overlaps - Returns true when both boxes share at least a point
+func (a AABB) Overlaps(b AABB) bool {
+	return a.Min.X <= b.Max.X && b.Min.X <= a.Max.X &&
+		a.Min.Y <= b.Max.Y && b.Min.Y <= a.Max.Y
+}
+
+// Pair type, two bodies that may be colliding. A comes before B in the bodies array
+type Pair struct {
+	A *Body
+	B *Body
+}
+
+// Broadphase finds the pairs of bodies whose shapes may touch, so the world
+// only solves the collisions of those. A Broadphase belongs to one World.
+type Broadphase interface {
+	// FindPairs appends the candidate pairs among bodies to pairs, each pair once
+	FindPairs(bodies []*Body, pairs []Pair) []Pair
+}
+
+// GetAABB - Returns the bounding box of a physics body shape
+func (b *Body) GetAABB() AABB {
+	if b.Shape.Type == CircleShape {
+		radius := rl.NewVector2(b.Shape.Radius, b.Shape.Radius)
+		return AABB{Min: rl.Vector2Subtract(b.Position, radius), Max: rl.Vector2Add(b.Position, radius)}
+	}
+
+	box := AABB{
+		Min: rl.NewVector2(math.MaxFloat32, math.MaxFloat32),
+		Max: rl.NewVector2(-math.MaxFloat32, -math.MaxFloat32),
+	}
+	for i := 0; i < b.Shape.VertexData.VertexCount; i++ {
+		vertex := b.GetShapeVertex(i)
+		box.Min = rl.NewVector2(min(box.Min.X, vertex.X), min(box.Min.Y, vertex.Y))
+		box.Max = rl.NewVector2(max(box.Max.X, vertex.X), max(box.Max.Y, vertex.Y))
+	}
+	return box
+}
+
+// orderPair - Returns the pair of a and b in the order of the bodies array
+func orderPair(a, b *Body) Pair {
+	if b.index < a.index {
+		a, b = b, a
+	}
+	return Pair{A: a, B: b}
+}
+
+type bruteForce struct{}
+
+// NewBruteForce creates a new instance of Broadphase pairing every body with
+// every other one, like the original engine. It only suits a few bodies.
+func NewBruteForce() Broadphase {
+	return bruteForce{}
+}
+
+func (bruteForce) FindPairs(bodies []*Body, pairs []Pair) []Pair {
+	for i, a := range bodies {
+		for _, b := range bodies[i+1:] {
+			pairs = append(pairs, Pair{A: a, B: b})
+		}
+	}
+	return pairs
+}
+
+type spatialHash struct {
+	cellSize float32
+	// buckets hold the index of the bodies covering the cells hashed to them
+	buckets [][]int32
+	boxes   []AABB
+	// large holds the index of the bodies covering too many cells to hash
+	large []int32
+}
+
+// NewSpatialHash creates a new instance of Broadphase sorting the bodies into
+// a uniform grid of square cells. Cells about the size of the common bodies
+// work best, bodies much larger than a cell cover many of them and bodies
+// covering more than maxHashCells are tested against every body.
+func NewSpatialHash(cellSize float32) Broadphase {
+	return &spatialHash{cellSize: max(cellSize, epsilon)}
+}
+
+func (s *spatialHash) FindPairs(bodies []*Body, pairs []Pair) []Pair {
+	// Twice as many buckets as bodies, in a power of two to mask the hash
+	size := 1
+	for size < 2*len(bodies) {
+		size *= 2
+	}
+	if len(s.buckets) != size {
+		s.buckets = make([][]int32, size)
+	}
+	for i := range s.buckets {
+		s.buckets[i] = s.buckets[i][:0]
+	}
+
+	s.boxes = s.boxes[:0]
+	s.large = s.large[:0]
+	for i, body := range bodies {
+		box := body.GetAABB()
+		s.boxes = append(s.boxes, box)
+		if s.oversized(box) {
+			s.large = append(s.large, int32(i))
+			continue
+		}
+		minX, minY, maxX, maxY := s.cellRange(box)
+		for y := minY; y <= maxY; y++ {
+			for x := minX; x <= maxX; x++ {
+				bucket := &s.buckets[s.hash(x, y)]
+				// Two cells of a body may share a bucket
+				if n := len(*bucket); n == 0 || (*bucket)[n-1] != int32(i) {
+					*bucket = append(*bucket, int32(i))
+				}
+			}
+		}
+	}
+
+	// A pair is reported by the cell holding the corner of the overlap, which
+	// both bodies cover, so it comes out once
+	for i, box := range s.boxes {
+		if s.oversized(box) {
+			continue
+		}
+		minX, minY, maxX, maxY := s.cellRange(box)
+		for y := minY; y <= maxY; y++ {
+			for x := minX; x <= maxX; x++ {
+				for _, j := range s.buckets[s.hash(x, y)] {
+					other := s.boxes[j]
+					if int(j) <= i || !box.Overlaps(other) {
+						continue
+					}
+					cornerX, cornerY := s.cell(max(box.Min.X, other.Min.X), max(box.Min.Y, other.Min.Y))
+					if cornerX == x && cornerY == y {
+						pairs = append(pairs, Pair{A: bodies[i], B: bodies[j]})
+					}
+				}
+			}
+		}
+	}
+
+	// Large bodies meet every body, a pair of them is reported by the first one
+	for _, i := range s.large {
+		box := s.boxes[i]
+		for j, other := range s.boxes {
+			if j == int(i) || (j < int(i) && s.oversized(other)) || !box.Overlaps(other) {
+				continue
+			}
+			pairs = append(pairs, orderPair(bodies[i], bodies[j]))
+		}
+	}
+	return pairs
+}
+
+// oversized - Returns true when a box covers more than maxHashCells cells
+func (s *spatialHash) oversized(box AABB) bool {
+	columns := math.Floor(float64(box.Max.X/s.cellSize)) - math.Floor(float64(box.Min.X/s.cellSize)) + 1
+	rows := math.Floor(float64(box.Max.Y/s.cellSize)) - math.Floor(float64(box.Min.Y/s.cellSize)) + 1
+	// Written to also catch the NaN of boxes with infinite bounds
+	return !(columns*rows <= maxHashCells)
+}
+
+// cell - Returns the coordinates of the cell holding a point
+func (s *spatialHash) cell(x, y float32) (int, int) {
+	return int(math.Floor(float64(x / s.cellSize))), int(math.Floor(float64(y / s.cellSize)))
+}
+
+// cellRange - Returns the inclusive range of cells covered by a box
+func (s *spatialHash) cellRange(box AABB) (int, int, int, int) {
+	minX, minY := s.cell(box.Min.X, box.Min.Y)
+	maxX, maxY := s.cell(box.Max.X, box.Max.Y)
+	return minX, minY, maxX, maxY
+}
+
+func (s *spatialHash) hash(x, y int) int {
+	return int((uint(x)*73856093 ^ uint(y)*19349663) & uint(len(s.buckets)-1))
+}
+
+type sweepAndPrune struct {
+	// order holds the body indices sorted along X by the last step, bodies
+	// move little between steps so it is nearly sorted for the next one
+	order  []int32
+	active []int32
+	boxes  []AABB
+}
+
+// NewSweepAndPrune creates a new instance of Broadphase sorting the bodies
+// along X and only pairing those whose extents overlap on both axes. It
+// needs no tuning and handles bodies of any size.
+func NewSweepAndPrune() Broadphase {
+	return &sweepAndPrune{}
+}
+
+func (s *sweepAndPrune) FindPairs(bodies []*Body, pairs []Pair) []Pair {
+	s.boxes = s.boxes[:0]
+	for _, body := range bodies {
+		s.boxes = append(s.boxes, body.GetAABB())
+	}
+
+	// Body indices always run from 0 to the bodies count, so any order of the
+	// same length is still a permutation of the bodies
+	if len(s.order) != len(bodies) {
+		s.order = s.order[:0]
+		for i := range bodies {
+			s.order = append(s.order, int32(i))
+		}
+		slices.SortStableFunc(s.order, func(a, b int32) int {
+			return compareFloat(s.boxes[a].Min.X, s.boxes[b].Min.X)
+		})
+	} else {
+		s.insertionSort()
+	}
+
+	s.active = s.active[:0]
+	for _, i := range s.order {
+		box := s.boxes[i]
+
+		// Drop the bodies ending before this one starts
+		kept := s.active[:0]
+		for _, j := range s.active {
+			if s.boxes[j].Max.X >= box.Min.X {
+				kept = append(kept, j)
+			}
+		}
+		s.active = kept
+
+		for _, j := range s.active {
+			other := s.boxes[j]
+			if box.Min.Y <= other.Max.Y && other.Min.Y <= box.Max.Y {
+				pairs = append(pairs, orderPair(bodies[i], bodies[j]))
+			}
+		}
+		s.active = append(s.active, i)
+	}
+	return pairs
+}
+
+// insertionSort - Sorts the nearly sorted order of the previous step by the box starts
+func (s *sweepAndPrune) insertionSort() {
+	for i := 1; i < len(s.order); i++ {
+		current := s.order[i]
+		j := i - 1
+		for ; j >= 0 && s.boxes[s.order[j]].Min.X > s.boxes[current].Min.X; j-- {
+			s.order[j+1] = s.order[j]
+		}
+		s.order[j+1] = current
+	}
+}
+
+func compareFloat(a, b float32) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,159 @@
+package physics
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// bodySize is the largest width of a test body, the spatial hash cells match it.
+const bodySize = 4
+
+// spacing is the room given to each body, it keeps the density of the
+// worlds the same whatever their size.
+const spacing = 8
+
+var broadphases = []struct {
+	name string
+	make func() Broadphase
+}{
+	{"brute", NewBruteForce},
+	{"hash", func() Broadphase { return NewSpatialHash(bodySize) }},
+	{"sap", NewSweepAndPrune},
+}
+
+// newTestWorld fills a square world without gravity with count circles,
+// boxes and pentagons moving about at random.
+func newTestWorld(broadphase Broadphase, count int, seed int64) World {
+	opts := DefaultWorldOptions()
+	opts.Gravity = rl.Vector2{}
+	opts.Broadphase = broadphase
+	world := NewWorld(opts)
+
+	random := rand.New(rand.NewSource(seed))
+	side := float32(spacing) * float32(ceilSqrt(count))
+	for i := 0; i < count; i++ {
+		pos := rl.NewVector2(random.Float32()*side, random.Float32()*side)
+		var body *Body
+		switch i % 3 {
+		case 0:
+			body = world.NewBodyCircle(pos, bodySize/2, 1)
+		case 1:
+			body = world.NewBodyRectangle(pos, bodySize, bodySize/2, 1)
+		default:
+			body = world.NewBodyPolygon(pos, bodySize/2, 5, 1)
+		}
+		body.Velocity = rl.NewVector2(random.Float32()-0.5, random.Float32()-0.5)
+		body.UseGravity = false
+	}
+	return world
+}
+
+func ceilSqrt(n int) int {
+	root := 1
+	for root*root < n {
+		root++
+	}
+	return root
+}
+
+// pairSet returns the pairs found by broadphase among bodies whose bounding
+// boxes overlap, failing on pairs reported twice.
+func pairSet(t *testing.T, broadphase Broadphase, bodies []*Body) map[Pair]bool {
+	t.Helper()
+	set := make(map[Pair]bool)
+	for _, pair := range broadphase.FindPairs(bodies, nil) {
+		if pair.A.index >= pair.B.index {
+			t.Fatalf("pair of bodies %d and %d is out of order", pair.A.ID, pair.B.ID)
+		}
+		if set[pair] {
+			t.Fatalf("pair of bodies %d and %d reported twice", pair.A.ID, pair.B.ID)
+		}
+		set[pair] = true
+	}
+	for pair := range set {
+		if !pair.A.GetAABB().Overlaps(pair.B.GetAABB()) {
+			delete(set, pair)
+		}
+	}
+	return set
+}
+
+func TestBroadphasePairs(t *testing.T) {
+	world := newTestWorld(NewSweepAndPrune(), 600, 1)
+	defer world.Close()
+	// A body covering most of the world and one far away from it
+	world.NewBodyRectangle(rl.NewVector2(100, 100), 150, 150, 1)
+	world.NewBodyCircle(rl.NewVector2(1e9, -1e9), bodySize/2, 1)
+	world.GetBodies()[5].Destroy()
+
+	// Keep each broadphase across steps, so sweep and prune sorts the order
+	// it persisted from the previous one
+	tested := make([]Broadphase, len(broadphases))
+	for i, b := range broadphases {
+		tested[i] = b.make()
+	}
+	for step := 0; step < 3; step++ {
+		bodies := world.GetBodies()
+		want := pairSet(t, NewBruteForce(), bodies)
+		if len(want) == 0 {
+			t.Fatal("no overlapping bodies to compare")
+		}
+		for i, b := range broadphases[1:] {
+			t.Run(fmt.Sprintf("%s/step%d", b.name, step), func(t *testing.T) {
+				got := pairSet(t, tested[i+1], bodies)
+				for pair := range want {
+					if !got[pair] {
+						t.Errorf("missed the pair of bodies %d and %d", pair.A.ID, pair.B.ID)
+					}
+				}
+				if len(got) != len(want) {
+					t.Errorf("found %d overlapping pairs, want %d", len(got), len(want))
+				}
+			})
+		}
+		for i := 0; i < 30; i++ {
+			world.Step()
+		}
+	}
+}
+
+func TestSpatialHashOversized(t *testing.T) {
+	world := newTestWorld(NewSweepAndPrune(), 50, 2)
+	defer world.Close()
+	huge := world.NewBodyRectangle(rl.NewVector2(0, 0), 1e7, 1e7, 1)
+	other := world.NewBodyRectangle(rl.NewVector2(1e6, 1e6), 1e7, 1e7, 1)
+
+	hash := NewSpatialHash(bodySize)
+	got := pairSet(t, hash, world.GetBodies())
+	for _, body := range world.GetBodies() {
+		if body != huge && !got[orderPair(huge, body)] {
+			t.Errorf("huge body not paired with body %d", body.ID)
+		}
+	}
+	if !got[orderPair(huge, other)] {
+		t.Error("the two huge bodies are not paired")
+	}
+	if len(hash.(*spatialHash).large) != 2 {
+		t.Errorf("%d large bodies, want the 2 huge ones", len(hash.(*spatialHash).large))
+	}
+}
+
+// BenchmarkStep times a physics step with each broadphase on worlds of
+// scattered bodies.
+func BenchmarkStep(b *testing.B) {
+	for _, broadphase := range broadphases {
+		for _, count := range []int{1000, 5000} {
+			b.Run(fmt.Sprintf("%s/%d", broadphase.name, count), func(b *testing.B) {
+				world := newTestWorld(broadphase.make(), count, 1)
+				defer world.Close()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					world.Step()
+				}
+			})
+		}
+	}
+}
@@ -1,11 +1,11 @@
 // Package physics - 2D Physics library for videogames
 //
 // A port of Victor Fisac's physac engine (https://github.com/raysan5/raylib/blob/master/src/physac.h)
+// where each World created by NewWorld runs its own simulation.
 package physics
 
 import (
 	"math"
-	"math/rand"
 	"time"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
@@ -86,11 +86,14 @@ type Body struct {
 	FreezeOrient bool
 	// Physics body shape information (type, radius, vertices, normals)
 	Shape Shape
+	// World the body lives in and the body position in its bodies array
+	world *world
+	index int
 }
 
 // Manifold type
 type Manifold struct {
-	// Reference unique identifier
+	// Index of the manifold among the manifolds of the current step
 	ID int
 	// Manifold first physics body reference
 	BodyA *Body
@@ -114,8 +117,6 @@ type Manifold struct {
 
 // Constants
 const (
-	maxBodies      = 64
-	maxManifolds   = 4096
 	maxVertices    = 24
 	circleVertices = 24
 
@@ -128,60 +129,121 @@ const (
 	physacK  = 1.0 / 3.0
 )
 
-// Globals
-var (
+// WorldOptions configures a new World
+type WorldOptions struct {
+	// Gravity force applied to bodies using gravity
+	Gravity rl.Vector2
+	// Fixed time step of the simulation, in milliseconds
+	TimeStep float32
+	// Broadphase finding the bodies that may collide, sweep and prune when nil
+	Broadphase Broadphase
+}
+
+// DefaultWorldOptions returns the gravity and time step of the original physac engine
+func DefaultWorldOptions() WorldOptions {
+	return WorldOptions{
+		Gravity:  rl.NewVector2(0, 9.81),
+		TimeStep: 1.0 / 60.0 / 10.0 * 1000,
+	}
+}
+
+// World is a physics simulation with its own bodies, manifolds, clock and
+// gravity. Worlds are independent of each other, so gameplay, effects and
+// tests can each run their own.
+type World interface {
+	GetGravity() rl.Vector2
+	SetGravity(x, y float32)
+	GetTimeStep() float32
+	SetTimeStep(delta float32)
+	GetBroadphase() Broadphase
+	SetBroadphase(broadphase Broadphase)
+	NewBodyCircle(pos rl.Vector2, radius, density float32) *Body
+	NewBodyRectangle(pos rl.Vector2, width, height, density float32) *Body
+	NewBodyPolygon(pos rl.Vector2, radius float32, sides int, density float32) *Body
+	Shatter(body *Body, position rl.Vector2, force float32)
+	GetBodies() []*Body
+	GetBodiesCount() int
+	GetBody(index int) *Body
+	GetBodyByID(id int) *Body
+	GetShapeType(index int) ShapeType
+	GetShapeVerticesCount(index int) int
+	Update()
+	Step()
+	Reset()
+	Close()
+}
+
+type world struct {
 	// Offset time for MONOTONIC clock
-	baseTime = time.Now()
+	baseTime time.Time
 
 	// Start time in milliseconds
 	startTime float32
 
 	// Delta time used for physics steps, in milliseconds
-	deltaTime float32 = 1.0 / 60.0 / 10.0 * 1000
-
-	// Current time in milliseconds
-	currentTime float32
-
-	// Hi-res clock frequency
-	frequency uint64 = 0
+	deltaTime float32
 
 	// Physics time step delta time accumulator
 	accumulator float32
 
 	// Physics world gravity force
-	gravityForce = rl.NewVector2(0, 9.81)
+	gravityForce rl.Vector2
 
-	// Physics bodies pointers array
-	bodies [64]*Body
+	// Physics bodies pointers array, in no particular order
+	bodies []*Body
 
-	// Physics world current bodies counter
-	bodiesCount int
+	// Physics bodies by id, nil for free ids
+	slots []*Body
 
-	// Physics bodies pointers array
-	manifolds [4096]*Manifold
+	// Ids of destroyed physics bodies, reused before new ones
+	freeIDs []int
 
-	// Physics world current manifolds counter
-	manifoldsCount int
-)
+	// Broadphase and the candidate pairs it found in the current step
+	broadphase Broadphase
+	pairs      []Pair
+
+	// Physics manifolds pointers array of the current step. The manifolds
+	// past its length are kept to be reused by the next steps
+	manifolds []*Manifold
+}
+
+// NewWorld creates a new instance of World with no bodies, its clock starting now
+func NewWorld(opts WorldOptions) World {
+	w := &world{
+		deltaTime:    opts.TimeStep,
+		gravityForce: opts.Gravity,
+	}
+	w.SetBroadphase(opts.Broadphase)
+	w.initTimer()
+	return w
+}
+
+// Getters and Setters for broadphase
+func (w *world) GetBroadphase() Broadphase {
+	return w.broadphase
+}
+
+func (w *world) SetBroadphase(broadphase Broadphase) {
+	if broadphase == nil {
+		broadphase = NewSweepAndPrune()
+	}
+	w.broadphase = broadphase
+}
 
-// Init - Initializes physics values, pointers and creates physics loop thread
-func Init() {
-	initTimer()
-	accumulator = 0
+// Getters and Setters for gravityForce
+func (w *world) GetGravity() rl.Vector2 {
+	return w.gravityForce
 }
 
 // SetGravity - Sets physics global gravity force
-func SetGravity(x, y float32) {
-	gravityForce.X = x
-	gravityForce.Y = y
+func (w *world) SetGravity(x, y float32) {
+	w.gravityForce.X = x
+	w.gravityForce.Y = y
 }
 
 // NewBodyCircle - Creates a new circle physics body with generic parameters
-func NewBodyCircle(pos rl.Vector2, radius, density float32) *Body {
-	newID := findAvailableBodyIndex()
-	if newID < 0 {
-		return nil
-	}
+func (w *world) NewBodyCircle(pos rl.Vector2, radius, density float32) *Body {
+	newID := w.newBodyID()
 
 	// Initialize new body with generic values
 	newBody := &Body{
@@ -214,18 +276,13 @@ func NewBodyCircle(pos rl.Vector2, radius, density float32) *Body {
 	newBody.Inertia = newBody.Mass * radius * radius
 	newBody.InverseInertia = safeDiv(1.0, newBody.Inertia)
 
-	// Add new body to bodies pointers array and update bodies count
-	bodies[bodiesCount] = newBody
-	bodiesCount++
+	w.addBody(newBody)
 	return newBody
 }
 
 // NewBodyRectangle - Creates a new rectangle physics body with generic parameters
-func NewBodyRectangle(pos rl.Vector2, width, height, density float32) *Body {
-	newID := findAvailableBodyIndex()
-	if newID < 0 {
-		return nil
-	}
+func (w *world) NewBodyRectangle(pos rl.Vector2, width, height, density float32) *Body {
+	newID := w.newBodyID()
 
 	// Initialize new body with generic values
 	newBody := &Body{
@@ -291,18 +348,13 @@ func NewBodyRectangle(pos rl.Vector2, width, height, density float32) *Body {
 	newBody.Inertia = density * inertia
 	newBody.InverseInertia = safeDiv(1.0, newBody.Inertia)
 
-	// Add new body to bodies pointers array and update bodies count
-	bodies[bodiesCount] = newBody
-	bodiesCount++
+	w.addBody(newBody)
 	return newBody
 }
 
 // NewBodyPolygon - Creates a new polygon physics body with generic parameters
-func NewBodyPolygon(pos rl.Vector2, radius float32, sides int, density float32) *Body {
-	newID := findAvailableBodyIndex()
-	if newID < 0 {
-		return nil
-	}
+func (w *world) NewBodyPolygon(pos rl.Vector2, radius float32, sides int, density float32) *Body {
+	newID := w.newBodyID()
 
 	// Initialize new body with generic values
 	newBody := &Body{
@@ -369,15 +421,14 @@ func NewBodyPolygon(pos rl.Vector2, radius float32, sides int, density float32)
 	newBody.Inertia = density * inertia
 	newBody.InverseInertia = safeDiv(1.0, newBody.Inertia)
 
-	// Add new body to bodies pointers array and update bodies count
-	bodies[bodiesCount] = newBody
-	bodiesCount++
+	w.addBody(newBody)
 	return newBody
 }
 
-// Reset - Destroys created physics bodies and manifolds
-func Reset() {
-	Close()
+// Reset - Destroys created physics bodies and manifolds and restarts the clock
+func (w *world) Reset() {
+	w.Close()
+	w.initTimer()
 }
 
 // AddForce - Adds a force to a physics body
@@ -395,8 +446,8 @@ func AddTorque(body *Body, amount float32) {
 }
 
 // Shatter - Shatters a polygon shape physics body to little physics bodies with explosion force
-func Shatter(body *Body, position rl.Vector2, force float32) {
-	if body == nil || body.Shape.Type != PolygonShape {
+func (w *world) Shatter(body *Body, position rl.Vector2, force float32) {
+	if body == nil || body.world != w || body.Shape.Type != PolygonShape {
 		return
 	}
 
@@ -445,7 +496,7 @@ func Shatter(body *Body, position rl.Vector2, force float32) {
 		center = rl.Vector2Add(bodyPos, center)
 		offset := rl.Vector2Subtract(center, bodyPos)
 
-		var newBody *Body = NewBodyPolygon(center, 10, 3, 10)
+		var newBody *Body = w.NewBodyPolygon(center, 10, 3, 10)
 		var newData Polygon = Polygon{}
 		newData.VertexCount = 3
 		newData.Positions[0] = rl.Vector2Subtract(vertices[i], offset)
@@ -525,41 +576,51 @@ func Shatter(body *Body, position rl.Vector2, force float32) {
 }
 
 // GetBodies - Returns the slice of created physics bodies
-func GetBodies() []*Body {
-	return bodies[:bodiesCount]
+func (w *world) GetBodies() []*Body {
+	return w.bodies[:len(w.bodies)]
 }
 
 // GetBodiesCount - Returns the current amount of created physics bodies
-func GetBodiesCount() int {
-	return bodiesCount
+func (w *world) GetBodiesCount() int {
+	return len(w.bodies)
+}
+
+// GetBody - Returns a physics body of the bodies pool at a specific index.
+// Indexes are not stable: destroying a body moves the last body into its
+// place, keep the body pointer or use GetBodyByID instead
+func (w *world) GetBody(index int) *Body {
+	return w.bodies[index]
 }
 
-// GetBody - Returns a physics body of the bodies pool at a specific index
-func GetBody(index int) *Body {
-	return bodies[index]
+// GetBodyByID - Returns the physics body with a specific id, nil when there is none
+func (w *world) GetBodyByID(id int) *Body {
+	if id < 0 || id >= len(w.slots) {
+		return nil
+	}
+	return w.slots[id]
 }
 
 // GetShapeType - Returns the physics body shape type (PHYSICS_CIRCLE or PHYSICS_POLYGON)
-func GetShapeType(index int) ShapeType {
+func (w *world) GetShapeType(index int) ShapeType {
 	result := ShapeType(-1)
-	if index < bodiesCount {
-		if bodies[index] != nil {
-			result = bodies[index].Shape.Type
+	if index < len(w.bodies) {
+		if w.bodies[index] != nil {
+			result = w.bodies[index].Shape.Type
 		}
 	}
 	return result
 }
 
 // GetShapeVerticesCount - Returns the amount of vertices of a physics body shape
-func GetShapeVerticesCount(index int) int {
+func (w *world) GetShapeVerticesCount(index int) int {
 	var result int = 0
-	if index < bodiesCount {
-		if bodies[index] != nil {
-			switch bodies[index].Shape.Type {
+	if index < len(w.bodies) {
+		if w.bodies[index] != nil {
+			switch w.bodies[index].Shape.Type {
 			case CircleShape:
 				result = circleVertices
 			case PolygonShape:
-				result = bodies[index].Shape.VertexData.VertexCount
+				result = w.bodies[index].Shape.VertexData.VertexCount
 			default:
 			}
 		}
@@ -593,65 +654,54 @@ func (b *Body) SetRotation(radians float32) {
 	}
 }
 
-// Destroy - Unitializes and destroy a physics body
+// Destroy - Unitializes and destroy a physics body. The last body of the
+// bodies array takes its place and its id is freed for a new body
 func (b *Body) Destroy() {
-	id := b.ID
-	index := -1
-	for i := 0; i < bodiesCount; i++ {
-		if bodies[i].ID == id {
-			index = i
-			break
-		}
-	}
-	if index == -1 {
+	w := b.world
+	if w == nil {
 		return
 	}
 
-	bodies[index] = nil
-
-	// Reorder physics bodies pointers array and its catched index
-	for i := index; i+1 < bodiesCount; i++ {
-		bodies[i] = bodies[i+1]
-	}
+	last := w.bodies[len(w.bodies)-1]
+	last.index = b.index
+	w.bodies[b.index] = last
+	w.bodies[len(w.bodies)-1] = nil
+	w.bodies = w.bodies[:len(w.bodies)-1]
 
-	// Update physics bodies count
-	bodiesCount--
+	w.slots[b.ID] = nil
+	w.freeIDs = append(w.freeIDs, b.ID)
+	b.world = nil
 }
 
 // Close - Unitializes physics pointers
-func Close() {
-	// Unitialize physics manifolds dynamic memory allocations
-	for i := manifoldsCount - 1; i >= 0; i-- {
-		destroyManifold(manifolds[i])
-	}
+func (w *world) Close() {
+	w.manifolds = nil
+	for _, body := range w.bodies {
+		body.world = nil
+	}
+	clear(w.bodies)
+	w.bodies = w.bodies[:0]
+	w.slots = nil
+	w.freeIDs = nil
+}
 
-	// Unitialize physics bodies dynamic memory allocations
-	for i := bodiesCount - 1; i >= 0; i-- {
-		bodies[i].Destroy()
+// newBodyID - Finds a valid id for a new physics body initialization
+func (w *world) newBodyID() int {
+	if n := len(w.freeIDs); n > 0 {
+		id := w.freeIDs[n-1]
+		w.freeIDs = w.freeIDs[:n-1]
+		return id
 	}
+	w.slots = append(w.slots, nil)
+	return len(w.slots) - 1
 }
 
-// findAvailableBodyIndex - Finds a valid index for a new physics body initialization
-func findAvailableBodyIndex() int {
-	index := -1
-	for i := 0; i < maxBodies; i++ {
-		currentID := i
-
-		// Check if current id already exist in other physics body
-		for k := 0; k < bodiesCount; k++ {
-			if bodies[k].ID == currentID {
-				currentID++
-				break
-			}
-		}
-
-		// If it is not used, use it as new physics body id
-		if currentID == i {
-			index = i
-			break
-		}
-	}
-	return index
+// addBody - Adds a new physics body to the bodies pointers array
+func (w *world) addBody(body *Body) {
+	body.world = w
+	body.index = len(w.bodies)
+	w.bodies = append(w.bodies, body)
+	w.slots[body.ID] = body
 }
 
 // createRandomPolygon - Creates a random polygon shape with max vertex distance from polygon pivot
@@ -700,203 +750,150 @@ func createRectanglePolygon(pos rl.Vector2, size rl.Vector2) Polygon {
 	return data
 }
 
-// step - Does physics steps calculations (dynamics, collisions and position corrections)
-func step() {
+// Step - Does one fixed time step of physics calculations (dynamics, collisions and position corrections)
+func (w *world) Step() {
 	// Clear previous generated collisions information
-	for i := manifoldsCount - 1; i >= 0; i-- {
-		if manifold := manifolds[i]; manifold != nil {
-			destroyManifold(manifold)
-		}
-	}
+	w.clearManifolds()
 
 	// Reset physics bodies grounded state
-	for i := 0; i < bodiesCount; i++ {
-		bodies[i].IsGrounded = false
+	for i := 0; i < len(w.bodies); i++ {
+		w.bodies[i].IsGrounded = false
 	}
 
-	// Generate new collision information
-	for i := 0; i < bodiesCount; i++ {
-		bodyA := bodies[i]
-		if bodyA == nil {
+	// Generate new collision information for the pairs found by the broadphase
+	w.pairs = w.broadphase.FindPairs(w.bodies, w.pairs[:0])
+	for _, pair := range w.pairs {
+		bodyA, bodyB := pair.A, pair.B
+		if bodyA.InverseMass == 0 && bodyB.InverseMass == 0 {
 			continue
 		}
 
-		for j := i + 1; j < bodiesCount; j++ {
-			var bodyB *Body = bodies[j]
-			if bodyB == nil || bodyA.InverseMass == 0 && bodyB.InverseMass == 0 {
-				continue
-			}
-
-			manifold := createManifold(bodyA, bodyB)
-			solveManifold(manifold)
-
-			if manifold.ContactsCount > 0 {
-				// Create a new manifold with same information as previously solved manifold and add it to the manifolds pool last slot
-				newManifold := createManifold(bodyA, bodyB)
-				newManifold.Penetration = manifold.Penetration
-				newManifold.Normal = manifold.Normal
-				newManifold.Contacts[0] = manifold.Contacts[0]
-				newManifold.Contacts[1] = manifold.Contacts[1]
-				newManifold.ContactsCount = manifold.ContactsCount
-				newManifold.Restitution = manifold.Restitution
-				newManifold.DynamicFriction = manifold.DynamicFriction
-				newManifold.StaticFriction = manifold.StaticFriction
-			}
+		manifold := w.createManifold(bodyA, bodyB)
+		solveManifold(manifold)
+
+		if manifold.ContactsCount > 0 {
+			// Create a new manifold with same information as previously solved manifold and add it to the manifolds pool last slot
+			newManifold := w.createManifold(bodyA, bodyB)
+			newManifold.Penetration = manifold.Penetration
+			newManifold.Normal = manifold.Normal
+			newManifold.Contacts[0] = manifold.Contacts[0]
+			newManifold.Contacts[1] = manifold.Contacts[1]
+			newManifold.ContactsCount = manifold.ContactsCount
+			newManifold.Restitution = manifold.Restitution
+			newManifold.DynamicFriction = manifold.DynamicFriction
+			newManifold.StaticFriction = manifold.StaticFriction
 		}
 	}
 
 	// Integrate forces to physics bodies
-	for i := 0; i < bodiesCount; i++ {
-		if body := bodies[i]; body != nil {
-			integrateForces(body)
+	for i := 0; i < len(w.bodies); i++ {
+		if body := w.bodies[i]; body != nil {
+			w.integrateForces(body)
 		}
 	}
 
 	// Initialize physics manifolds to solve collisions
-	for i := 0; i < manifoldsCount; i++ {
-		if manifold := manifolds[i]; manifold != nil {
-			initializeManifolds(manifold)
+	for i := 0; i < len(w.manifolds); i++ {
+		if manifold := w.manifolds[i]; manifold != nil {
+			w.initializeManifolds(manifold)
 		}
 	}
 
 	// Integrate physics collisions impulses to solve collisions
 	for i := 0; i < collisionIterations; i++ {
-		for j := 0; j < manifoldsCount; j++ {
-			if manifold := manifolds[i]; manifold != nil {
+		for j := 0; j < len(w.manifolds); j++ {
+			if i >= len(w.manifolds) {
+				break
+			}
+			if manifold := w.manifolds[i]; manifold != nil {
 				integrateImpulses(manifold)
 			}
 		}
 	}
 
 	// Integrate velocity to physics bodies
-	for i := 0; i < bodiesCount; i++ {
-		if body := bodies[i]; body != nil {
-			integrateVelocity(body)
+	for i := 0; i < len(w.bodies); i++ {
+		if body := w.bodies[i]; body != nil {
+			w.integrateVelocity(body)
 		}
 	}
 
 	// Correct physics bodies positions based on manifolds collision information
-	for i := 0; i < manifoldsCount; i++ {
-		if manifold := manifolds[i]; manifold != nil {
+	for i := 0; i < len(w.manifolds); i++ {
+		if manifold := w.manifolds[i]; manifold != nil {
 			correctPositions(manifold)
 		}
 	}
 
 	// Clear physics bodies forces
-	for i := 0; i < bodiesCount; i++ {
-		if body := bodies[i]; body != nil {
+	for i := 0; i < len(w.bodies); i++ {
+		if body := w.bodies[i]; body != nil {
 			body.Force = rl.Vector2{}
 			body.Torque = 0
 		}
 	}
 }
 
-// Update - Runs physics step
-func Update() {
+// Update - Runs the physics steps due since the last update
+func (w *world) Update() {
 	// Calculate current time
-	currentTime = getCurrentTime()
+	currentTime := w.getCurrentTime()
 
 	// Calculate current delta time
-	var delta float32 = currentTime - startTime
+	var delta float32 = currentTime - w.startTime
 
 	// Store the time elapsed since the last frame began
-	accumulator += delta
+	w.accumulator += delta
 
 	// Fixed time stepping loop
-	for accumulator >= deltaTime {
-		step()
-		accumulator -= deltaTime
+	for w.accumulator >= w.deltaTime {
+		w.Step()
+		w.accumulator -= w.deltaTime
 	}
 
 	// Record the starting of this frame
-	startTime = currentTime
+	w.startTime = currentTime
 }
 
-// SetTimeStep - Sets physics fixed time step in milliseconds. 1.666666 by default
-func SetTimeStep(delta float32) {
-	deltaTime = delta
+// Getters and Setters for deltaTime
+func (w *world) GetTimeStep() float32 {
+	return w.deltaTime
 }
 
-// findAvailableManifoldIndex - Finds a valid index for a new manifold initialization
-func findAvailableManifoldIndex() int {
-	index := -1
-	for i := 0; i < maxManifolds; i++ {
-		var currentId int = i
-
-		// Check if current id already exist in other physics body
-		for k := 0; k < manifoldsCount; k++ {
-			if manifolds[k].ID == currentId {
-				currentId++
-				break
-			}
-		}
-
-		// If it is not used, use it as new physics body id
-		if currentId == i {
-			index = i
-			break
-		}
-	}
-	return index
+// SetTimeStep - Sets physics fixed time step in milliseconds. 1.666666 by default
+func (w *world) SetTimeStep(delta float32) {
+	w.deltaTime = delta
 }
 
 // createManifold - Creates a new physics manifold to solve collision
-func createManifold(a *Body, b *Body) *Manifold {
-	newID := findAvailableManifoldIndex()
-	if newID < 0 {
-		return nil
+func (w *world) createManifold(a *Body, b *Body) *Manifold {
+	newID := len(w.manifolds)
+
+	// Reuse a manifold left by a previous step when there is one
+	var newManifold *Manifold
+	if newID < cap(w.manifolds) {
+		newManifold = w.manifolds[:newID+1][newID]
+	}
+	if newManifold == nil {
+		newManifold = &Manifold{}
 	}
 
 	// Initialize new manifold with generic values
-	newManifold := &Manifold{
-		ID:              newID,
-		BodyA:           a,
-		BodyB:           b,
-		Penetration:     0,
-		Normal:          rl.Vector2{},
-		Contacts:        [2]rl.Vector2{},
-		ContactsCount:   0,
-		Restitution:     0.0,
-		DynamicFriction: 0.0,
-		StaticFriction:  0.0,
+	*newManifold = Manifold{
+		ID:    newID,
+		BodyA: a,
+		BodyB: b,
 	}
 
-	// Add new contact to conctas pointers array and update contacts count
-	manifolds[manifoldsCount] = newManifold
-	manifoldsCount++
+	// Add new contact to conctas pointers array
+	w.manifolds = append(w.manifolds, newManifold)
 
 	return newManifold
 }
 
-// destroyManifold - Unitializes and destroys a physics manifold
-func destroyManifold(manifold *Manifold) {
-	if manifold == nil {
-		return
-	}
-
-	id := manifold.ID
-	index := -1
-	for i := 0; i < manifoldsCount; i++ {
-		if manifolds[i].ID == id {
-			index = i
-			break
-		}
-	}
-	if index < 0 {
-		return
-	}
-
-	manifolds[index] = nil
-
-	// Reorder physics manifolds pointers array and its catched index
-	for i := index; i < manifoldsCount; i++ {
-		if (i + 1) < manifoldsCount {
-			manifolds[i] = manifolds[i+1]
-		}
-	}
-
-	// Update physics manifolds count
-	manifoldsCount--
+// clearManifolds - Unitializes the physics manifolds, keeping their memory for the next step
+func (w *world) clearManifolds() {
+	w.manifolds = w.manifolds[:0]
 }
 
 // solveManifold - Solves a created physics manifold between two physics bodies
@@ -1199,26 +1196,26 @@ func solvePolygonToPolygon(manifold *Manifold) {
 }
 
 // integrateForces - Integrates physics forces into velocity
-func integrateForces(body *Body) {
+func (w *world) integrateForces(body *Body) {
 	if body == nil || body.InverseMass == 0 || !body.Enabled {
 		return
 	}
 
-	body.Velocity.X += body.Force.X * body.InverseMass * (deltaTime / 2.0)
-	body.Velocity.Y += body.Force.Y * body.InverseMass * (deltaTime / 2.0)
+	body.Velocity.X += body.Force.X * body.InverseMass * (w.deltaTime / 2.0)
+	body.Velocity.Y += body.Force.Y * body.InverseMass * (w.deltaTime / 2.0)
 
 	if body.UseGravity {
-		body.Velocity.X += gravityForce.X * (deltaTime / 1000 / 2.0)
-		body.Velocity.Y += gravityForce.Y * (deltaTime / 1000 / 2.0)
+		body.Velocity.X += w.gravityForce.X * (w.deltaTime / 1000 / 2.0)
+		body.Velocity.Y += w.gravityForce.Y * (w.deltaTime / 1000 / 2.0)
 	}
 
 	if !body.FreezeOrient {
-		body.AngularVelocity += body.Torque * body.InverseInertia * (deltaTime / 2.0)
+		body.AngularVelocity += body.Torque * body.InverseInertia * (w.deltaTime / 2.0)
 	}
 }
 
 // initializeManifolds - Initializes physics manifolds to solve collisions
-func initializeManifolds(manifold *Manifold) {
+func (w *world) initializeManifolds(manifold *Manifold) {
 	bodyA, bodyB := manifold.BodyA, manifold.BodyB
 
 	if bodyA == nil || bodyB == nil {
@@ -1245,7 +1242,7 @@ func initializeManifolds(manifold *Manifold) {
 		// Determine if we should perform a resting collision or not;
 		// The idea is if the only thing moving this object is gravity, then the collision should be
 		// performed without any restitution
-		rad := rl.NewVector2(gravityForce.X*deltaTime/1000, gravityForce.Y*deltaTime/1000)
+		rad := rl.NewVector2(w.gravityForce.X*w.deltaTime/1000, w.gravityForce.Y*w.deltaTime/1000)
 		if rl.Vector2LenSqr(radiusV) < (rl.Vector2LenSqr(rad) + epsilon) {
 			manifold.Restitution = 0
 		}
@@ -1381,21 +1378,21 @@ func integrateImpulses(manifold *Manifold) {
 }
 
 // integrateVelocity - Integrates physics velocity into position and forces
-func integrateVelocity(body *Body) {
+func (w *world) integrateVelocity(body *Body) {
 	if body == nil || !body.Enabled {
 		return
 	}
 
-	body.Position.X += body.Velocity.X * deltaTime
-	body.Position.Y += body.Velocity.Y * deltaTime
+	body.Position.X += body.Velocity.X * w.deltaTime
+	body.Position.Y += body.Velocity.Y * w.deltaTime
 
 	if !body.FreezeOrient {
-		body.Orient += body.AngularVelocity * deltaTime
+		body.Orient += body.AngularVelocity * w.deltaTime
 	}
 
 	rl.Mat2Set(&body.Shape.Transform, body.Orient)
 
-	integrateForces(body)
+	w.integrateForces(body)
 }
 
 // correctPositions - Corrects physics bodies positions based on manifolds collision information
@@ -1559,20 +1556,15 @@ func triangleBarycenter(v1 rl.Vector2, v2 rl.Vector2, v3 rl.Vector2) rl.Vector2
 }
 
 // initTimer - Initializes hi-resolution MONOTONIC timer
-func initTimer() {
-	rand.Seed(getTimeCount())
-	frequency = 1000000000
-	startTime = getCurrentTime() // Get current time
-}
-
-// getTimeCount - Gets hi-res MONOTONIC time measure in nanoseconds
-func getTimeCount() int64 {
-	return time.Since(baseTime).Nanoseconds()
+func (w *world) initTimer() {
+	w.baseTime = time.Now()
+	w.startTime = w.getCurrentTime() // Get current time
+	w.accumulator = 0
 }
 
 // getCurrentTime - Gets current time measure in milliseconds
-func getCurrentTime() float32 {
-	return float32(getTimeCount()) / float32(frequency) * 1000
+func (w *world) getCurrentTime() float32 {
+	return float32(time.Since(w.baseTime).Nanoseconds()) / 1e6
 }
 
 // normalize - Returns the normalized values of a vector
@@ -0,0 +1,138 @@
+package physics
+
+import (
+	"math/rand"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// checkStorage fails when the bodies array, the body indexes and the ids
+// of world disagree.
+func checkStorage(t *testing.T, w World) {
+	t.Helper()
+	for i, body := range w.GetBodies() {
+		if body.index != i || w.GetBody(i) != body {
+			t.Fatalf("body %d is at index %d, recorded %d", body.ID, i, body.index)
+		}
+		if w.GetBodyByID(body.ID) != body {
+			t.Fatalf("GetBodyByID(%d) does not return the body", body.ID)
+		}
+	}
+}
+
+func TestBodyStorage(t *testing.T) {
+	const count = 5000
+	w := NewWorld(DefaultWorldOptions())
+	defer w.Close()
+	random := rand.New(rand.NewSource(1))
+
+	ids := make(map[*Body]int)
+	for i := 0; i < count; i++ {
+		body := w.NewBodyCircle(rl.NewVector2(random.Float32()*100, random.Float32()*100), 1, 1)
+		if body.ID != i {
+			t.Fatalf("body %d got id %d", i, body.ID)
+		}
+		ids[body] = body.ID
+	}
+	checkStorage(t, w)
+
+	// Destroy half of the bodies in random order
+	destroyed := make(map[int]bool)
+	for _, i := range random.Perm(count)[:count/2] {
+		body := w.GetBodyByID(i)
+		body.Destroy()
+		destroyed[i] = true
+		delete(ids, body)
+		if w.GetBodyByID(i) != nil {
+			t.Fatalf("destroyed body %d is still found by id", i)
+		}
+	}
+	if got := w.GetBodiesCount(); got != count-count/2 {
+		t.Fatalf("%d bodies left, want %d", got, count-count/2)
+	}
+	checkStorage(t, w)
+	for body, id := range ids {
+		if body.ID != id {
+			t.Fatalf("body id changed from %d to %d", id, body.ID)
+		}
+	}
+
+	// New bodies take the freed ids before growing the id range
+	for i := 0; i < count/2; i++ {
+		body := w.NewBodyRectangle(rl.NewVector2(0, 0), 1, 1, 1)
+		if !destroyed[body.ID] {
+			t.Fatalf("new body got id %d instead of a freed one", body.ID)
+		}
+		delete(destroyed, body.ID)
+	}
+	if body := w.NewBodyCircle(rl.NewVector2(0, 0), 1, 1); body.ID != count {
+		t.Fatalf("body created with no free id got id %d, want %d", body.ID, count)
+	}
+	checkStorage(t, w)
+}
+
+func BenchmarkCreateDestroy(b *testing.B) {
+	w := NewWorld(DefaultWorldOptions())
+	defer w.Close()
+	for i := 0; i < 10000; i++ {
+		w.NewBodyCircle(rl.NewVector2(float32(i), 0), 1, 1)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Destroying the first body moves the last one, it never shifts the rest
+		w.GetBody(0).Destroy()
+		w.NewBodyCircle(rl.NewVector2(0, 0), 1, 1)
+	}
+}
+
+// newStackWorld returns a world holding a static floor and a box falling on
+// it, with ids 0 and 1.
+func newStackWorld(gravity float32) (World, *Body) {
+	opts := DefaultWorldOptions()
+	opts.Gravity = rl.NewVector2(0, gravity)
+	w := NewWorld(opts)
+	floor := w.NewBodyRectangle(rl.NewVector2(0, 10), 40, 2, 1)
+	floor.Enabled = false
+	box := w.NewBodyRectangle(rl.NewVector2(0, 0), 2, 2, 1)
+	return w, box
+}
+
+func TestWorldsIndependent(t *testing.T) {
+	reference, referenceBox := newStackWorld(9.81)
+	defer reference.Close()
+	falling, fallingBox := newStackWorld(9.81)
+	defer falling.Close()
+	still, stillBox := newStackWorld(0)
+	defer still.Close()
+	if fallingBox.ID != stillBox.ID {
+		t.Fatalf("worlds gave their boxes ids %d and %d, want the same", fallingBox.ID, stillBox.ID)
+	}
+
+	for i := 0; i < 50; i++ {
+		reference.Step()
+		falling.Step()
+		// Stepping and changing one world leaves the others alone
+		still.Step()
+		still.NewBodyCircle(rl.NewVector2(float32(i), -20), 1, 1).Destroy()
+	}
+	if fallingBox.Position != referenceBox.Position || fallingBox.Velocity != referenceBox.Velocity {
+		t.Errorf("box at %v moving %v, alone it is at %v moving %v",
+			fallingBox.Position, fallingBox.Velocity, referenceBox.Position, referenceBox.Velocity)
+	}
+	if fallingBox.Position.Y <= 0 {
+		t.Errorf("box did not fall, it is at %v", fallingBox.Position)
+	}
+	if stillBox.Position != rl.NewVector2(0, 0) {
+		t.Errorf("box without gravity moved to %v", stillBox.Position)
+	}
+
+	// Destroying a body frees its id in its own world only
+	stillBox.Destroy()
+	if falling.GetBodyByID(fallingBox.ID) != fallingBox {
+		t.Error("destroying a body of one world removed the body with its id from another")
+	}
+	if still.GetBodiesCount() != 1 || falling.GetBodiesCount() != 2 {
+		t.Errorf("worlds hold %d and %d bodies, want 1 and 2", still.GetBodiesCount(), falling.GetBodiesCount())
+	}
+}
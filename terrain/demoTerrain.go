@@ -9,3 +9,8 @@ import (
 func CreateDemoTerrain() {
 	rl.DrawGrid(cts.Slices, cts.Spacing)
 }
+
+// GetHeight returns the height of the demo terrain at x, z
+func GetHeight(x, z float32) float32 {
+	return 0
+}
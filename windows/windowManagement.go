@@ -1,10 +1,16 @@
 package windows
 
 import (
+	"fmt"
 	camera "main/camera"
 	cts "main/constants"
+	"main/debug"
 	"main/entity"
+	"main/level"
+	f "main/pathfinder"
 	world "main/world"
+	"os"
+	"path/filepath"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
@@ -31,15 +37,32 @@ func (w *windows) Close() {
 }
 
 func (w *windows) Process() {
-	playerData := entity.NewPlayer()
-	cameraData := camera.NewCamera3D()
 	treeData := entity.NewTree()
+	navGrid := world.CreateNavGrid(treeData.GetHitBox())
+	pathService := f.NewService(cts.PathWorkers, cts.PathQueueSize)
+	pathCache := f.NewPathCache(navGrid, cts.PathCacheSize)
+	demoLevel := loadLevel(cts.LevelFile)
+	costMap := loadCostMap(demoLevel)
+	playerData := entity.NewPlayer(navGrid, pathService, pathCache, costMap)
+	// The mesh is cut for the player's size but has no terrain costs, so
+	// the player walks the grid and its cost map unless asked otherwise
+	if cts.PlayerNavMesh {
+		playerData.GetAgent().SetNavMesh(loadNavMesh(demoLevel, treeData.GetHitBox()))
+	}
+	npcs := loadNPCs(demoLevel, navGrid, pathService, pathCache, costMap)
+	cameraData := camera.NewCamera3D()
+	pathDebug := debug.NewPathDebug(navGrid)
+	debugMode := true
 
 	for !rl.WindowShouldClose() {
+		pathService.Dispatch() // Apply finished path queries on the game loop
 		playerData.HandleCollison(treeData.GetHitBox())
 		cameraData.UpdateCamera()
 		playerData.KeyboardMovement()
-		// playerData.MouseMovement(camera.NewCamera3D().GetCamera())
+		if debugMode {
+			pathDebug.HandleInput(playerData.GetAgent())
+		}
+		playerData.MouseMovement(cameraData.GetCamera())
 		rl.BeginDrawing()
 
 		rl.ClearBackground(rl.White)
@@ -50,14 +73,83 @@ func (w *windows) Process() {
 		world.CreateWorld()
 		playerData.Process()
 		treeData.Process()
-		playerData.DebugMode(true)
-		treeData.DebugMode(true)
+		for _, npc := range npcs {
+			npc.Process()
+			npc.DebugMode(debugMode)
+		}
+		playerData.DebugMode(debugMode)
+		treeData.DebugMode(debugMode)
+		pathDebug.DebugMode(debugMode, playerData.GetAgent())
 
 		rl.EndMode3D()
 		rl.DrawFPS(10, 10)
 		//--------------------------------------------------------------------------------------
 		rl.EndDrawing()
 	}
+	defer pathCache.Close()
+	defer pathService.Close()
 	defer playerData.CleanUp()
 	defer treeData.CleanUp()
 }
+
+// loadLevel reads the level at path, nil when it cannot be loaded.
+func loadLevel(path string) *level.Level {
+	demoLevel, err := level.Load(path)
+	if err != nil {
+		fmt.Println("Level:", err)
+		return nil
+	}
+	return demoLevel
+}
+
+// loadCostMap builds the terrain costs of demoLevel. Paths ignore terrain
+// costs when there is no level or its costs cannot be built.
+func loadCostMap(demoLevel *level.Level) f.CostMap {
+	if demoLevel == nil {
+		return nil
+	}
+	costMap, err := demoLevel.CostMap()
+	if err != nil {
+		fmt.Println("Level:", err)
+		return nil
+	}
+	return costMap
+}
+
+// loadNavMesh loads the navigation mesh baked for demoLevel in the user
+// cache directory. When there is none, or it was built for other obstacles
+// or settings, it is built around obstacles and baked for the next run.
+func loadNavMesh(demoLevel *level.Level, obstacles ...rl.BoundingBox) f.NavMesh {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		fmt.Println("Level:", err)
+		return world.CreateNavMesh(obstacles...)
+	}
+	name := "world"
+	if demoLevel != nil {
+		name = demoLevel.Name
+	}
+	path := filepath.Join(cacheDir, cts.NavMeshCacheDir, name+".navmesh.json")
+	navMesh, err := f.LoadOrBuildNavMesh(path, world.NavMeshConfig(), obstacles...)
+	if err != nil {
+		fmt.Println("Level:", err)
+	}
+	return navMesh
+}
+
+// loadNPCs creates an NPC walking each patrol route of demoLevel.
+func loadNPCs(demoLevel *level.Level, navGrid f.NavGrid, pathService f.Service, pathCache f.PathCache, costMap f.CostMap) []entity.NPC {
+	if demoLevel == nil {
+		return nil
+	}
+	var npcs []entity.NPC
+	for _, patrol := range demoLevel.Patrols {
+		route, err := patrol.Route()
+		if err != nil {
+			fmt.Println("Level:", err)
+			continue
+		}
+		npcs = append(npcs, entity.NewNPC(navGrid, pathService, pathCache, costMap, route))
+	}
+	return npcs
+}
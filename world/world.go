@@ -1,8 +1,45 @@
 package world
 
-import terrain "main/terrain"
+import (
+	cts "main/constants"
+	f "main/pathfinder"
+	terrain "main/terrain"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
 
 func CreateWorld() {
 	terrain.CreateDemoTerrain()
 
 }
+
+// CreateNavGrid builds the navigation grid covering the demo terrain with
+// the given obstacles blocked out.
+func CreateNavGrid(obstacles ...rl.BoundingBox) f.NavGrid {
+	half := float32(cts.Slices) * cts.Spacing / 2
+	navGrid := f.NewNavGridFromBounds(rl.NewVector3(-half, 0, -half), rl.NewVector3(half, cts.NavHeight, half), cts.CellSize)
+	navGrid.SetHeightFunc(terrain.GetHeight)
+	for _, obstacle := range obstacles {
+		navGrid.AddObstacle(obstacle)
+	}
+	return navGrid
+}
+
+// NavMeshConfig describes the navigation mesh covering the demo terrain,
+// cut out around the player's size.
+func NavMeshConfig() f.NavMeshConfig {
+	half := float32(cts.Slices) * cts.Spacing / 2
+	return f.NavMeshConfig{
+		Min:         rl.NewVector3(-half, 0, -half),
+		Max:         rl.NewVector3(half, 0, half),
+		AgentRadius: cts.NavAgentRadius,
+		AgentHeight: cts.NavAgentHeight,
+		Height:      terrain.GetHeight,
+	}
+}
+
+// CreateNavMesh builds the navigation mesh covering the demo terrain with
+// the given obstacles cut out.
+func CreateNavMesh(obstacles ...rl.BoundingBox) f.NavMesh {
+	return f.BuildNavMesh(NavMeshConfig(), obstacles...)
+}